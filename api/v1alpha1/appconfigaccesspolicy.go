@@ -0,0 +1,218 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AccessDeniedError lists the keys the policies of a namespace don't allow to
+// read, or to write if Write is set.
+// +kubebuilder:object:generate=false
+type AccessDeniedError struct {
+	Namespace string
+	Denied    []string
+	Write     bool
+}
+
+func (e *AccessDeniedError) Error() string {
+	access := "read"
+	if e.Write {
+		access = "write"
+	}
+	return fmt.Sprintf("the AppConfigAccessPolicies of namespace %s don't allow to %s %s", e.Namespace, access, strings.Join(e.Denied, ", "))
+}
+
+// AccessPolicies are the policies selecting a namespace. Once any policy
+// exists, a namespace no policy selects may not access any setting. Without
+// policies the access isn't restricted.
+// +kubebuilder:object:generate=false
+type AccessPolicies struct {
+	Namespace string
+	Policies  []AppConfigAccessPolicy
+	// restricted is set if any policy exists, selecting the namespace or not.
+	restricted bool
+}
+
+// AccessPoliciesFor returns the policies selecting the namespace. The namespace
+// is only read if there are policies.
+func AccessPoliciesFor(ctx context.Context, reader client.Reader, namespace string) (*AccessPolicies, error) {
+	list := &AppConfigAccessPolicyList{}
+	if err := reader.List(ctx, list); err != nil {
+		return nil, fmt.Errorf("failed to list AppConfigAccessPolicies: %w", err)
+	}
+	policies := &AccessPolicies{Namespace: namespace}
+	if len(list.Items) == 0 {
+		return policies, nil
+	}
+	policies.restricted = true
+
+	ns := &corev1.Namespace{}
+	if err := reader.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		return nil, fmt.Errorf("failed to read Namespace %s: %w", namespace, err)
+	}
+	for _, p := range list.Items {
+		selector, err := metav1.LabelSelectorAsSelector(&p.Spec.NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("AppConfigAccessPolicy %s: %w", p.Name, err)
+		}
+		if selector.Matches(labels.Set(ns.Labels)) {
+			policies.Policies = append(policies.Policies, p)
+		}
+	}
+	return policies, nil
+}
+
+// allows reports whether a rule of the policies allows reading, or writing if
+// write is set, the setting of the store. A nil content type isn't known yet
+// and not checked.
+func (p *AccessPolicies) allows(store, key, label string, contentType *string, write bool) bool {
+	if !p.restricted {
+		return true
+	}
+	for _, policy := range p.Policies {
+		for _, rule := range policy.Spec.Rules {
+			if rule.allows(store, key, label, contentType, write) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r AccessRule) allows(store, key, label string, contentType *string, write bool) bool {
+	if write && !r.Write {
+		return false
+	}
+	if !oneOf(r.Stores, store, func(a, b string) bool { return a == b }) {
+		return false
+	}
+	prefixed := false
+	for _, prefix := range r.KeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			prefixed = true
+			break
+		}
+	}
+	if !prefixed || !oneOf(r.Labels, label, func(a, b string) bool { return a == b }) {
+		return false
+	}
+	return contentType == nil || oneOf(r.ContentTypes, mediaType(*contentType), func(a, b string) bool {
+		return strings.EqualFold(mediaType(a), b)
+	})
+}
+
+// oneOf reports whether the value is one of the values, any value is if empty.
+func oneOf(values []string, value string, equal func(a, b string) bool) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if equal(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaType strips the parameters, like the charset, from the content type.
+func mediaType(contentType string) string {
+	mt, _, _ := strings.Cut(contentType, ";")
+	return strings.TrimSpace(mt)
+}
+
+// CheckSpec returns an AccessDeniedError if the keys or paths of the spec, read
+// from its store with its label, aren't allowed. A path without label reads
+// settings with any label, it is checked as reading settings without label.
+func (p *AccessPolicies) CheckSpec(spec *ParameterStoreSpec) error {
+	var denied []string
+	if ref := spec.ValueFrom.ParameterStoreRef; ref != nil {
+		for _, key := range []string{ref.Name, ref.Path} {
+			if key != "" && !p.allows(spec.Store, key, spec.Label, nil, false) {
+				denied = append(denied, key)
+			}
+		}
+	}
+	for _, ref := range spec.ValueFrom.ParametersStoreRef {
+		if !p.allows(spec.Store, ref.Key, spec.Label, nil, false) {
+			denied = append(denied, ref.Key)
+		}
+	}
+	return p.denied(denied)
+}
+
+// CheckKeys returns an AccessDeniedError if the settings synced from the store
+// aren't allowed, including their actual label and content type.
+func (p *AccessPolicies) CheckKeys(store string, keys []KeyStatus) error {
+	var denied []string
+	for _, k := range keys {
+		if k.State == KeyStateSynced && !p.allows(store, k.Key, k.Label, &k.ContentType, false) {
+			denied = append(denied, k.Key)
+		}
+	}
+	return p.denied(denied)
+}
+
+// Denied returns the keys whose settings of the store aren't allowed. The
+// content type is only checked for synced keys, it isn't known for the others.
+func (p *AccessPolicies) Denied(store string, keys []KeyStatus) []KeyStatus {
+	var denied []KeyStatus
+	for _, k := range keys {
+		var contentType *string
+		if k.State == KeyStateSynced {
+			contentType = &k.ContentType
+		}
+		if !p.allows(store, k.Key, k.Label, contentType, false) {
+			denied = append(denied, k)
+		}
+	}
+	return denied
+}
+
+// CheckPush returns an AccessDeniedError if the settings pushed to the store of
+// the operator with the content type aren't allowed to be written.
+func (p *AccessPolicies) CheckPush(keys []PushedKeyStatus, contentType string) error {
+	var denied []string
+	for _, k := range keys {
+		if !p.AllowsPush(k, contentType) {
+			denied = append(denied, k.Key)
+		}
+	}
+	if len(denied) == 0 {
+		return nil
+	}
+	return &AccessDeniedError{Namespace: p.Namespace, Denied: denied, Write: true}
+}
+
+// AllowsPush reports whether the pushed setting may be written or deleted.
+func (p *AccessPolicies) AllowsPush(k PushedKeyStatus, contentType string) bool {
+	return p.allows("", k.Key, k.Label, &contentType, true)
+}
+
+func (p *AccessPolicies) denied(keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return &AccessDeniedError{Namespace: p.Namespace, Denied: keys}
+}
@@ -0,0 +1,135 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// testReader returns a fake reader knowing the AppConfigAccessPolicies.
+func testReader(t *testing.T, objs ...client.Object) client.Reader {
+	s := runtime.NewScheme()
+	assert.Nil(t, clientgoscheme.AddToScheme(s))
+	assert.Nil(t, AddToScheme(s))
+	return fake.NewClientBuilder().WithScheme(s).WithObjects(objs...).Build()
+}
+
+func paymentsPolicy() *AppConfigAccessPolicy {
+	return &AppConfigAccessPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments"},
+		Spec: AppConfigAccessPolicySpec{
+			NamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+			Rules: []AccessRule{
+				{KeyPrefixes: []string{"/prod/payments/"}, Labels: []string{"prod"}},
+				{KeyPrefixes: []string{"/shared/"}, ContentTypes: []string{"text/plain"}},
+			},
+		},
+	}
+}
+
+func TestAccessPolicies(t *testing.T) {
+	reader := testReader(t,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments", Labels: map[string]string{"team": "payments"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+		paymentsPolicy())
+
+	// Namespaces no policy selects may not read anything once any policy exists.
+	policies, err := AccessPoliciesFor(context.TODO(), reader, "other")
+	assert.Nil(t, err)
+	err = policies.CheckSpec(&ParameterStoreSpec{ValueFrom: ValueFrom{ParametersStoreRef: []ParametersStoreRef{{Key: "/prod/orders/url"}}}})
+	assert.Equal(t, &AccessDeniedError{Namespace: "other", Denied: []string{"/prod/orders/url"}}, err)
+
+	// Without any policy the access isn't restricted.
+	policies, err = AccessPoliciesFor(context.TODO(), testReader(t), "other")
+	assert.Nil(t, err)
+	assert.Nil(t, policies.CheckSpec(&ParameterStoreSpec{ValueFrom: ValueFrom{ParametersStoreRef: []ParametersStoreRef{{Key: "/prod/orders/url"}}}}))
+
+	policies, err = AccessPoliciesFor(context.TODO(), reader, "payments")
+	assert.Nil(t, err)
+	assert.Len(t, policies.Policies, 1)
+
+	assert.Nil(t, policies.CheckSpec(&ParameterStoreSpec{Label: "prod", ValueFrom: ValueFrom{ParametersStoreRef: []ParametersStoreRef{{Key: "/prod/payments/db"}, {Key: "/shared/region"}}}}))
+	err = policies.CheckSpec(&ParameterStoreSpec{Label: "dev", ValueFrom: ValueFrom{
+		ParameterStoreRef:  &ParameterStoreRef{Path: "/prod/"},
+		ParametersStoreRef: []ParametersStoreRef{{Key: "/prod/payments/db"}, {Key: "/prod/orders/url"}},
+	}})
+	assert.Equal(t, &AccessDeniedError{Namespace: "payments", Denied: []string{"/prod/", "/prod/payments/db", "/prod/orders/url"}}, err)
+
+	err = policies.CheckKeys("", []KeyStatus{
+		{Key: "/prod/payments/db", Label: "prod", ContentType: "application/json", State: KeyStateSynced},
+		{Key: "/shared/region", ContentType: "Text/Plain; charset=utf-8", State: KeyStateSynced},
+		{Key: "/shared/cert", ContentType: "application/x-pem-file", State: KeyStateSynced},
+		{Key: "/prod/orders/db", State: KeyStateMissing},
+	})
+	assert.Equal(t, &AccessDeniedError{Namespace: "payments", Denied: []string{"/shared/cert"}}, err)
+}
+
+func TestAccessPoliciesStores(t *testing.T) {
+	policy := paymentsPolicy()
+	policy.Spec.Rules = []AccessRule{{KeyPrefixes: []string{"/dev/"}, Stores: []string{"", "payments-dev"}}}
+	reader := testReader(t,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments", Labels: map[string]string{"team": "payments"}}},
+		policy)
+	policies, err := AccessPoliciesFor(context.TODO(), reader, "payments")
+	assert.Nil(t, err)
+
+	// The keys are only allowed from the store of the operator and the listed stores.
+	spec := &ParameterStoreSpec{ValueFrom: ValueFrom{ParametersStoreRef: []ParametersStoreRef{{Key: "/dev/db"}}}}
+	assert.Nil(t, policies.CheckSpec(spec))
+	spec.Store = "payments-dev"
+	assert.Nil(t, policies.CheckSpec(spec))
+	spec.Store = "payments-prod"
+	assert.Equal(t, &AccessDeniedError{Namespace: "payments", Denied: []string{"/dev/db"}}, policies.CheckSpec(spec))
+
+	keys := []KeyStatus{{Key: "/dev/db", State: KeyStateSynced}}
+	assert.Nil(t, policies.CheckKeys("payments-dev", keys))
+	assert.Equal(t, &AccessDeniedError{Namespace: "payments", Denied: []string{"/dev/db"}}, policies.CheckKeys("payments-prod", keys))
+}
+
+func TestValidateAccess(t *testing.T) {
+	validator := &parameterStoreValidator{reader: testReader(t,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments", Labels: map[string]string{"team": "payments"}}},
+		paymentsPolicy())}
+
+	ps := &ParameterStore{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "payments"},
+		Spec:       ParameterStoreSpec{Label: "prod", ValueFrom: ValueFrom{ParametersStoreRef: []ParametersStoreRef{{Key: "/prod/payments/db"}}}},
+	}
+	_, err := validator.ValidateCreate(context.TODO(), ps)
+	assert.Nil(t, err)
+
+	updated := ps.DeepCopy()
+	updated.Spec.ValueFrom.ParametersStoreRef = append(updated.Spec.ValueFrom.ParametersStoreRef, ParametersStoreRef{Key: "/prod/orders/url"})
+	_, err = validator.ValidateUpdate(context.TODO(), ps, updated)
+	assert.True(t, apierrors.IsForbidden(err))
+	assert.ErrorContains(t, err, "/prod/orders/url")
+}
+
+func TestAccessPoliciesPush(t *testing.T) {
+	policy := paymentsPolicy()
+	policy.Spec.Rules = append(policy.Spec.Rules, AccessRule{KeyPrefixes: []string{"/prod/payments/push/"}, Stores: []string{"payments-dev"}, Write: true})
+	reader := testReader(t,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments", Labels: map[string]string{"team": "payments"}}},
+		policy)
+	policies, err := AccessPoliciesFor(context.TODO(), reader, "payments")
+	assert.Nil(t, err)
+
+	// Rules only allow to write with write, in the store of the operator.
+	err = policies.CheckPush([]PushedKeyStatus{{Key: "/prod/payments/db", Label: "prod"}, {Key: "/prod/payments/push/db"}}, "")
+	assert.Equal(t, &AccessDeniedError{Namespace: "payments", Denied: []string{"/prod/payments/db", "/prod/payments/push/db"}, Write: true}, err)
+	assert.EqualError(t, err, "the AppConfigAccessPolicies of namespace payments don't allow to write /prod/payments/db, /prod/payments/push/db")
+
+	policy.Spec.Rules[2].Stores = []string{""}
+	assert.Nil(t, reader.(client.Client).Update(context.TODO(), policy))
+	policies, err = AccessPoliciesFor(context.TODO(), reader, "payments")
+	assert.Nil(t, err)
+	assert.Nil(t, policies.CheckPush([]PushedKeyStatus{{Key: "/prod/payments/push/db"}}, ""))
+}
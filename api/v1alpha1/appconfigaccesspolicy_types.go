@@ -0,0 +1,78 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AppConfigAccessPolicySpec allows the namespaces selected by the namespace
+// selector to read the settings matching one of the rules. Once any policy
+// exists, the ParameterStores of a namespace may only read the settings allowed
+// by the policies selecting it, none if no policy selects it.
+type AppConfigAccessPolicySpec struct {
+	// NamespaceSelector selects the namespaces the policy applies to, an empty
+	// selector selects all namespaces.
+	NamespaceSelector metav1.LabelSelector `json:"namespaceSelector"`
+	// Rules are the settings the namespaces may read.
+	// +kubebuilder:validation:MinItems=1
+	Rules []AccessRule `json:"rules"`
+}
+
+// AccessRule allows reading the settings with one of the key prefixes, labels
+// and content types.
+type AccessRule struct {
+	// KeyPrefixes are the prefixes of the keys, e.g. /prod/payments/.
+	// +kubebuilder:validation:MinItems=1
+	KeyPrefixes []string `json:"keyPrefixes"`
+	// Stores are the App Configuration stores the settings may be read from,
+	// "" is the store of the operator. Any store is allowed if empty.
+	// +kubebuilder:validation:Optional
+	Stores []string `json:"stores,omitempty"`
+	// Labels are the labels the settings may have, "" are settings without
+	// label. Any label is allowed if empty.
+	// +kubebuilder:validation:Optional
+	Labels []string `json:"labels,omitempty"`
+	// ContentTypes are the content types the settings may have, "" are settings
+	// without content type. Any content type is allowed if empty.
+	// +kubebuilder:validation:Optional
+	ContentTypes []string `json:"contentTypes,omitempty"`
+	// Write allows the PushToAppConfigs of the namespaces to write and delete
+	// the settings too, in the store of the operator.
+	// +kubebuilder:validation:Optional
+	Write bool `json:"write,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+
+// AppConfigAccessPolicy is the Schema for the appconfigaccesspolicies API
+type AppConfigAccessPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AppConfigAccessPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// AppConfigAccessPolicyList contains a list of AppConfigAccessPolicy
+type AppConfigAccessPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AppConfigAccessPolicy `json:"items"`
+}
@@ -38,7 +38,7 @@ var (
 
 // addKnownTypes adds the types in this group-version to the given scheme.
 func addKnownTypes(scheme *runtime.Scheme) error {
-	scheme.AddKnownTypes(GroupVersion, &ParameterStore{}, &ParameterStoreList{})
+	scheme.AddKnownTypes(GroupVersion, &ParameterStore{}, &ParameterStoreList{}, &PushToAppConfig{}, &PushToAppConfigList{}, &AppConfigAccessPolicy{}, &AppConfigAccessPolicyList{})
 	metav1.AddToGroupVersion(scheme, GroupVersion)
 	return nil
 }
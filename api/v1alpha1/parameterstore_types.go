@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"strings"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -31,6 +33,61 @@ const (
 	ConditionTypeSSMParamMissing string = "SSMParamMissing"
 	ConditionTypeSSMError        string = "SSMError"
 	ConditionTypeReady           string = "Ready"
+
+	// Strategies deriving the keys of the Secret from the keys of the settings.
+	KeyMappingUpperSnakeCase string = "UpperSnakeCase"
+	KeyMappingLastSegment    string = "LastSegment"
+	KeyMappingFullKey        string = "FullKey"
+
+	FailurePolicyFail          string = "Fail"
+	FailurePolicyPartial       string = "Partial"
+	FailurePolicyKeepLastKnown string = "KeepLastKnown"
+
+	// States of the keys in the status.
+	KeyStateSynced    string = "Synced"
+	KeyStateDefaulted string = "Defaulted"
+	KeyStateMissing   string = "Missing"
+	KeyStateLastKnown string = "LastKnown"
+	KeyStateFailed    string = "Failed"
+	// ConditionTypeFieldManagerConflict is set when keys of the Secret are
+	// owned by an other field manager and can't be applied.
+	ConditionTypeFieldManagerConflict string = "FieldManagerConflict"
+	// ConditionTypeDrifted is set when the Secret was modified or deleted outside
+	// of the operator and had to be restored.
+	ConditionTypeDrifted string = "Drifted"
+	// ConditionTypeStale is set when the last sync failed and the Secret holds
+	// the data of the last successful sync.
+	ConditionTypeStale string = "Stale"
+	// ConditionTypeDegraded is set when the Secret is stale for longer than the max staleness.
+	ConditionTypeDegraded string = "Degraded"
+	// ConditionTypeDryRun is set when the sync of a ParameterStore in dry-run
+	// mode succeeded and the changes of the Secret are in the status.
+	ConditionTypeDryRun string = "DryRun"
+	// ConditionTypeForbidden is set when the AppConfigAccessPolicies of the
+	// namespace don't allow the ParameterStore to read its keys.
+	ConditionTypeForbidden string = "Forbidden"
+
+	SyncFailedReason           string = "SyncFailed"
+	MaxStalenessExceededReason string = "MaxStalenessExceeded"
+
+	// Reasons of failed conditions, derived from the class of the App Configuration error.
+	KeyNotFoundReason    string = "KeyNotFound"
+	UnauthorizedReason   string = "Unauthorized"
+	ForbiddenReason      string = "Forbidden"
+	ThrottledReason      string = "Throttled"
+	TransientErrorReason string = "TransientError"
+	InvalidRefReason     string = "InvalidRef"
+	AccessDeniedReason   string = "AccessDenied"
+
+	SecretModifiedReason string = "SecretModified"
+	SecretDeletedReason  string = "SecretDeleted"
+	SecretRestoredReason string = "SecretRestored"
+
+	// Reasons of the events about the sync of the Secret.
+	SecretCreatedReason     string = "SecretCreated"
+	SecretUpdatedReason     string = "SecretUpdated"
+	AuthFailedReason        string = "AuthFailed"
+	CollisionDetectedReason string = "CollisionDetected"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -42,6 +99,61 @@ type ParameterStoreSpec struct {
 	// Important: Run "make" to regenerate code after modifying this file
 
 	ValueFrom ValueFrom `json:"valueFrom"`
+
+	// RolloutTargets are workloads that are restarted when the data of the Secret changes.
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=kind
+	// +listMapKey=name
+	RolloutTargets []RolloutTarget `json:"rolloutTargets,omitempty"`
+	// AutoRollout restarts all Deployments, StatefulSets and DaemonSets in the namespace
+	// that reference the Secret via env, envFrom or volumes when the data of the Secret changes.
+	// +kubebuilder:validation:Optional
+	AutoRollout bool `json:"autoRollout,omitempty"`
+	// FailurePolicy decides what happens if keys of the ParametersStoreRef can't be fetched.
+	// Fail doesn't write the Secret, Partial writes it without the failed keys and
+	// KeepLastKnown writes it with the values the failed keys had in the Secret before.
+	// +kubebuilder:validation:Enum=Fail;Partial;KeepLastKnown
+	// +kubebuilder:default:=Fail
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+	// MaxStaleness is how long the Secret may keep the data of the last successful
+	// sync while syncing fails, before the ParameterStore is Degraded.
+	// +kubebuilder:validation:Optional
+	MaxStaleness *metav1.Duration `json:"maxStaleness,omitempty"`
+	// DeleteStaleSecret removes the Secret, or the keys written by the operator of
+	// a shared Secret, once the ParameterStore is Degraded.
+	// +kubebuilder:validation:Optional
+	DeleteStaleSecret bool `json:"deleteStaleSecret,omitempty"`
+	// Store is the name of the App Configuration store the values are read from,
+	// the store of the operator if empty.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9-]{5,50}$`
+	Store string `json:"store,omitempty"`
+	// Label selects the settings with the label. If empty, keys are read without
+	// label and paths with any label.
+	// +kubebuilder:validation:Optional
+	Label string `json:"label,omitempty"`
+	// KeyMapping derives the keys of the Secret from the keys of the settings read
+	// by path or without name. UpperSnakeCase upper-cases the last segment of the
+	// key and replaces - by _, LastSegment keeps the last segment and FullKey
+	// replaces the / of the whole key by _. UpperSnakeCase if empty.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=UpperSnakeCase;LastSegment;FullKey
+	KeyMapping string `json:"keyMapping,omitempty"`
+	// RefreshInterval is the interval the values are synced in, even if neither
+	// the ParameterStore nor the Secret changed.
+	// +kubebuilder:validation:Optional
+	RefreshInterval *metav1.Duration `json:"refreshInterval,omitempty"`
+	// DryRun fetches and maps the settings without writing the Secret, the keys
+	// the sync would add, remove or change are written to the status instead.
+	// +kubebuilder:validation:Optional
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+type RolloutTarget struct {
+	// +kubebuilder:validation:Enum=Deployment;StatefulSet;DaemonSet
+	Kind string `json:"kind"`
+	Name string `json:"name"`
 }
 
 type ValueFrom struct {
@@ -51,6 +163,7 @@ type ValueFrom struct {
 	ParametersStoreRef []ParametersStoreRef `json:"parametersStoreRef"`
 }
 
+// +kubebuilder:validation:XValidation:rule="(has(self.name) && self.name != '') != (has(self.path) && self.path != '')",message="exactly one of name or path must be set"
 type ParameterStoreRef struct {
 	Name string `json:"name,omitempty"`
 	Path string `json:"path,omitempty"`
@@ -59,8 +172,42 @@ type ParameterStoreRef struct {
 }
 
 type ParametersStoreRef struct {
+	// Name is the key in the Secret, derived from the last segment of the key if empty.
 	Name string `json:"name,omitempty"`
-	Key  string `json:"key"`
+	// +kubebuilder:validation:MinLength=1
+	Key string `json:"key"`
+	// Optional keys that don't exist are left out of the Secret instead of failing the sync.
+	// +kubebuilder:validation:Optional
+	Optional bool `json:"optional,omitempty"`
+	// Default is the value used if the key doesn't exist, it makes the key optional.
+	// +kubebuilder:validation:Optional
+	Default *string `json:"default,omitempty"`
+}
+
+// SecretKey returns the key of the value in the Secret.
+func (r ParametersStoreRef) SecretKey(keyMapping string) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return MapKey(keyMapping, r.Key)
+}
+
+// SecretKey derives the key of the Secret from the last segment of the setting key.
+func SecretKey(key string) string {
+	return MapKey(KeyMappingUpperSnakeCase, key)
+}
+
+// MapKey derives the key of the Secret from the setting key with the key mapping strategy.
+func MapKey(keyMapping, key string) string {
+	ss := strings.Split(key, "/")
+	switch keyMapping {
+	case KeyMappingLastSegment:
+		return ss[len(ss)-1]
+	case KeyMappingFullKey:
+		return strings.ReplaceAll(strings.Trim(key, "/"), "/", "_")
+	}
+	name := strings.ToUpper(ss[len(ss)-1])
+	return strings.ReplaceAll(name, "-", "_")
 }
 
 // ParameterStoreStatus defines the observed state of ParameterStore
@@ -70,6 +217,16 @@ type ParameterStoreStatus struct {
 	SecretStatus *SecretStatus      `json:"secret,omitempty"`
 	SSMStatus    *SSMStatus         `json:"ssm,omitempty"`
 	Conditions   []metav1.Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation of the spec the status was computed for.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// SyncedKeys is the number of keys written to the Secret by the last sync.
+	SyncedKeys int32 `json:"syncedKeys,omitempty"`
+	// LastSyncTime is the time of the last successful sync.
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// DryRun is the effect the last sync in dry-run mode would have on the Secret.
+	DryRun *DryRunStatus `json:"dryRun,omitempty"`
+	// LastChange is the change of the data of the Secret by the last sync that changed it.
+	LastChange *SecretChangeStatus `json:"lastChange,omitempty"`
 }
 
 type SecretStatus struct {
@@ -77,18 +234,71 @@ type SecretStatus struct {
 	Namespace string `json:"namespace,omitempty"`
 }
 
+// DryRunStatus lists the keys of the Secret a sync would add, remove or change,
+// never their values.
+type DryRunStatus struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	// Error is the error reading the settings, if the dry-run failed.
+	Error string `json:"error,omitempty"`
+	// Keys are the settings the keys would be synced from, or the errors
+	// fetching them. The keys of the last sync are kept in the status.
+	Keys []KeyStatus `json:"keys,omitempty"`
+	// Time is the time the result of the dry-run last changed.
+	Time metav1.Time `json:"time"`
+}
+
+// SecretChangeStatus lists the keys of the Secret a sync added, removed or
+// changed and the settings responsible, never their values.
+type SecretChangeStatus struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	// Sources are the settings the added and changed keys were synced from,
+	// and the removed keys were synced from before.
+	Sources []ChangeSource `json:"sources,omitempty"`
+	// Time is the time of the change.
+	Time metav1.Time `json:"time"`
+}
+
+// ChangeSource is the App Configuration setting of a key of the Secret.
+type ChangeSource struct {
+	// Name is the key in the Secret.
+	Name  string `json:"name"`
+	Key   string `json:"key,omitempty"`
+	Label string `json:"label,omitempty"`
+	ETag  string `json:"etag,omitempty"`
+}
+
 type SSMStatus struct {
 	Error string      `json:"error,omitempty"`
 	Key   []KeyStatus `json:"keys,omitempty"`
 }
 
+// KeyStatus is the state of a key of the Secret, either the App Configuration
+// setting it was synced from or the error fetching it.
 type KeyStatus struct {
+	// Name is the key in the Secret.
 	Name  string `json:"name,omitempty"`
 	Error string `json:"error,omitempty"`
+	// State is one of Synced, Defaulted, Missing, LastKnown or Failed.
+	State string `json:"state,omitempty"`
+	// Key is the App Configuration key the value was read from.
+	Key          string       `json:"key,omitempty"`
+	Label        string       `json:"label,omitempty"`
+	ETag         string       `json:"etag,omitempty"`
+	ContentType  string       `json:"contentType,omitempty"`
+	LastModified *metav1.Time `json:"lastModified,omitempty"`
+	Locked       bool         `json:"locked,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Keys",type=integer,JSONPath=`.status.syncedKeys`
+//+kubebuilder:printcolumn:name="LastSync",type=date,JSONPath=`.status.lastSyncTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // ParameterStore is the Schema for the parameterstores API
 type ParameterStore struct {
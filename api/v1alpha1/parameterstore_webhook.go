@@ -0,0 +1,218 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// Annotations of a Namespace holding the defaults of the ParameterStores in it.
+const (
+	DefaultStoreAnnotation           = "appconfig.azure.io/default-store"
+	DefaultLabelAnnotation           = "appconfig.azure.io/default-label"
+	DefaultKeyMappingAnnotation      = "appconfig.azure.io/default-key-mapping"
+	DefaultRefreshIntervalAnnotation = "appconfig.azure.io/default-refresh-interval"
+)
+
+// storeName matches the names of App Configuration stores, like the pattern of spec.store.
+var storeName = regexp.MustCompile(`^[a-zA-Z0-9-]{5,50}$`)
+
+// ValidStoreName reports whether name is a valid App Configuration store name.
+// The name is part of the host name of the store, so nothing else may be sent
+// to it.
+func ValidStoreName(name string) bool {
+	return storeName.MatchString(name)
+}
+
+// SetupWebhookWithManager registers the defaulting and the validating webhook of the ParameterStore.
+func (r *ParameterStore) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, r).
+		WithDefaulter(&parameterStoreDefaulter{reader: mgr.GetAPIReader()}).
+		WithValidator(&parameterStoreValidator{reader: mgr.GetAPIReader()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-ssm-aws-v1alpha1-parameterstore,mutating=true,failurePolicy=fail,sideEffects=None,groups=ssm.aws,resources=parameterstores,verbs=create;update,versions=v1alpha1,name=mparameterstore.kb.io,admissionReviewVersions=v1
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get
+
+// parameterStoreDefaulter fills the fields left empty from the annotations of the Namespace.
+type parameterStoreDefaulter struct {
+	reader client.Reader
+}
+
+func (d *parameterStoreDefaulter) Default(ctx context.Context, obj *ParameterStore) error {
+	return DefaultFromNamespace(ctx, d.reader, obj)
+}
+
+// DefaultFromNamespace fills the fields of the spec left empty from the
+// annotations of the Namespace of the ParameterStore.
+func DefaultFromNamespace(ctx context.Context, reader client.Reader, ps *ParameterStore) error {
+	namespace := requestNamespace(ctx, ps.Namespace)
+	ns := &corev1.Namespace{}
+	if err := reader.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		return fmt.Errorf("failed to read the defaults of Namespace %s: %w", namespace, err)
+	}
+	return ps.Spec.Default(ns.Annotations)
+}
+
+// requestNamespace returns the namespace of the object, objects created without
+// namespace get the namespace of the request.
+func requestNamespace(ctx context.Context, namespace string) string {
+	if namespace == "" {
+		if req, err := admission.RequestFromContext(ctx); err == nil {
+			return req.Namespace
+		}
+	}
+	return namespace
+}
+
+// Default fills the fields left empty from the default annotations. Invalid
+// annotation values are reported instead of being applied.
+func (s *ParameterStoreSpec) Default(annotations map[string]string) error {
+	if v, ok := annotations[DefaultStoreAnnotation]; ok && s.Store == "" {
+		if v != "" && !ValidStoreName(v) {
+			return fmt.Errorf("annotation %s: invalid store name %q", DefaultStoreAnnotation, v)
+		}
+		s.Store = v
+	}
+	if v, ok := annotations[DefaultLabelAnnotation]; ok && s.Label == "" {
+		s.Label = v
+	}
+	if v, ok := annotations[DefaultKeyMappingAnnotation]; ok && s.KeyMapping == "" {
+		switch v {
+		case KeyMappingUpperSnakeCase, KeyMappingLastSegment, KeyMappingFullKey:
+			s.KeyMapping = v
+		default:
+			return fmt.Errorf("annotation %s: unsupported key mapping %q", DefaultKeyMappingAnnotation, v)
+		}
+	}
+	if v, ok := annotations[DefaultRefreshIntervalAnnotation]; ok && s.RefreshInterval == nil {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("annotation %s: invalid refresh interval %q", DefaultRefreshIntervalAnnotation, v)
+		}
+		s.RefreshInterval = &metav1.Duration{Duration: d}
+	}
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-ssm-aws-v1alpha1-parameterstore,mutating=false,failurePolicy=fail,sideEffects=None,groups=ssm.aws,resources=parameterstores,verbs=create;update,versions=v1alpha1,name=vparameterstore.kb.io,admissionReviewVersions=v1
+//+kubebuilder:rbac:groups=ssm.aws,resources=appconfigaccesspolicies,verbs=get;list;watch
+
+// parameterStoreValidator rejects specs that can't be synced, or read keys the
+// AppConfigAccessPolicies of the namespace don't allow, before they are stored.
+type parameterStoreValidator struct {
+	reader client.Reader
+}
+
+func (v *parameterStoreValidator) ValidateCreate(ctx context.Context, obj *ParameterStore) (admission.Warnings, error) {
+	if err := obj.Validate(); err != nil {
+		return nil, err
+	}
+	return nil, CheckAccess(ctx, v.reader, obj)
+}
+
+func (v *parameterStoreValidator) ValidateUpdate(ctx context.Context, oldObj, newObj *ParameterStore) (admission.Warnings, error) {
+	if err := newObj.Validate(); err != nil {
+		return nil, err
+	}
+	return nil, CheckAccess(ctx, v.reader, newObj)
+}
+
+func (v *parameterStoreValidator) ValidateDelete(ctx context.Context, obj *ParameterStore) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// Validate returns the field errors of the spec as an Invalid error.
+func (r *ParameterStore) Validate() error {
+	errs := r.Spec.Validate(field.NewPath("spec"))
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("ParameterStore").GroupKind(), r.Name, errs)
+}
+
+// CheckAccess returns a Forbidden error if the AppConfigAccessPolicies of the
+// namespace don't allow the keys of the spec.
+func CheckAccess(ctx context.Context, reader client.Reader, ps *ParameterStore) error {
+	policies, err := AccessPoliciesFor(ctx, reader, requestNamespace(ctx, ps.Namespace))
+	if err != nil {
+		return err
+	}
+	if err := policies.CheckSpec(&ps.Spec); err != nil {
+		return apierrors.NewForbidden(GroupVersion.WithResource("parameterstores").GroupResource(), ps.Name, err)
+	}
+	return nil
+}
+
+// Validate returns the errors of the fields of the spec below path.
+func (s *ParameterStoreSpec) Validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	valueFrom := path.Child("valueFrom")
+
+	if s.Store != "" && !ValidStoreName(s.Store) {
+		errs = append(errs, field.Invalid(path.Child("store"), s.Store, "must be 5 to 50 letters, digits or -"))
+	}
+
+	if ref := s.ValueFrom.ParameterStoreRef; ref != nil {
+		refPath := valueFrom.Child("parameterStoreRef")
+		switch {
+		case ref.Name == "" && ref.Path == "":
+			errs = append(errs, field.Required(refPath, "one of name or path must be set"))
+		case ref.Name != "" && ref.Path != "":
+			errs = append(errs, field.Invalid(refPath.Child("path"), ref.Path, "name and path are mutually exclusive"))
+		}
+	}
+
+	names := make(map[string]bool, len(s.ValueFrom.ParametersStoreRef))
+	for i, ref := range s.ValueFrom.ParametersStoreRef {
+		refPath := valueFrom.Child("parametersStoreRef").Index(i)
+		if ref.Key == "" {
+			errs = append(errs, field.Required(refPath.Child("key"), ""))
+			continue
+		}
+		name := ref.SecretKey(s.KeyMapping)
+		if names[name] {
+			errs = append(errs, field.Duplicate(refPath.Child("name"), name))
+		}
+		names[name] = true
+	}
+
+	if s.RefreshInterval != nil && s.RefreshInterval.Duration <= 0 {
+		errs = append(errs, field.Invalid(path.Child("refreshInterval"), s.RefreshInterval.Duration.String(), "must be positive"))
+	}
+
+	targets := make(map[RolloutTarget]bool, len(s.RolloutTargets))
+	for i, target := range s.RolloutTargets {
+		if targets[target] {
+			errs = append(errs, field.Duplicate(path.Child("rolloutTargets").Index(i), target.Kind+"/"+target.Name))
+		}
+		targets[target] = true
+	}
+	return errs
+}
@@ -0,0 +1,132 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		spec   ParameterStoreSpec
+		fields []string
+	}{
+		{
+			name: "valid",
+			spec: ParameterStoreSpec{
+				ValueFrom: ValueFrom{
+					ParameterStoreRef:  &ParameterStoreRef{Path: "/app"},
+					ParametersStoreRef: []ParametersStoreRef{{Name: "DB_USER", Key: "user"}, {Key: "db/password"}},
+				},
+				RolloutTargets: []RolloutTarget{{Kind: "Deployment", Name: "app"}, {Kind: "StatefulSet", Name: "app"}},
+			},
+		},
+		{
+			name:   "neither name nor path",
+			spec:   ParameterStoreSpec{ValueFrom: ValueFrom{ParameterStoreRef: &ParameterStoreRef{}}},
+			fields: []string{"spec.valueFrom.parameterStoreRef"},
+		},
+		{
+			name:   "name and path",
+			spec:   ParameterStoreSpec{ValueFrom: ValueFrom{ParameterStoreRef: &ParameterStoreRef{Name: "user", Path: "/app"}}},
+			fields: []string{"spec.valueFrom.parameterStoreRef.path"},
+		},
+		{
+			name:   "empty key",
+			spec:   ParameterStoreSpec{ValueFrom: ValueFrom{ParametersStoreRef: []ParametersStoreRef{{Name: "DB_USER"}}}},
+			fields: []string{"spec.valueFrom.parametersStoreRef[0].key"},
+		},
+		{
+			name: "duplicate names",
+			spec: ParameterStoreSpec{ValueFrom: ValueFrom{ParametersStoreRef: []ParametersStoreRef{
+				{Name: "PASSWORD", Key: "app1/password"},
+				{Key: "app2/password"},
+			}}},
+			fields: []string{"spec.valueFrom.parametersStoreRef[1].name"},
+		},
+		{
+			name:   "negative refresh interval",
+			spec:   ParameterStoreSpec{RefreshInterval: &metav1.Duration{Duration: -time.Minute}},
+			fields: []string{"spec.refreshInterval"},
+		},
+		{
+			name:   "store outside of the host name",
+			spec:   ParameterStoreSpec{Store: "evil.example/x?"},
+			fields: []string{"spec.store"},
+		},
+		{
+			name:   "duplicate rollout targets",
+			spec:   ParameterStoreSpec{RolloutTargets: []RolloutTarget{{Kind: "Deployment", Name: "app"}, {Kind: "Deployment", Name: "app"}}},
+			fields: []string{"spec.rolloutTargets[1]"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ps := &ParameterStore{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Spec: tc.spec}
+			_, err := (&parameterStoreValidator{reader: testReader(t)}).ValidateCreate(context.TODO(), ps)
+			if len(tc.fields) == 0 {
+				assert.Nil(t, err)
+				return
+			}
+			assert.True(t, apierrors.IsInvalid(err))
+			var fields []string
+			for _, cause := range err.(*apierrors.StatusError).ErrStatus.Details.Causes {
+				fields = append(fields, cause.Field)
+			}
+			assert.Equal(t, tc.fields, fields)
+		})
+	}
+}
+
+func TestSecretKey(t *testing.T) {
+	assert.Equal(t, "DB_PASSWORD", SecretKey("/app/db-password"))
+	assert.Equal(t, "USER", ParametersStoreRef{Key: "app/user"}.SecretKey(""))
+	assert.Equal(t, "DB_USER", ParametersStoreRef{Name: "DB_USER", Key: "app/user"}.SecretKey(KeyMappingFullKey))
+	assert.Equal(t, "db-password", MapKey(KeyMappingLastSegment, "/app/db-password"))
+	assert.Equal(t, "app_db-password", MapKey(KeyMappingFullKey, "/app/db-password"))
+}
+
+func TestDefault(t *testing.T) {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team", Annotations: map[string]string{
+		DefaultStoreAnnotation:           "team-store",
+		DefaultLabelAnnotation:           "prod",
+		DefaultKeyMappingAnnotation:      KeyMappingLastSegment,
+		DefaultRefreshIntervalAnnotation: "5m",
+	}}}
+	defaulter := &parameterStoreDefaulter{reader: fake.NewClientBuilder().WithObjects(ns).Build()}
+
+	ps := &ParameterStore{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "team"}}
+	assert.Nil(t, defaulter.Default(context.TODO(), ps))
+	assert.Equal(t, "team-store", ps.Spec.Store)
+	assert.Equal(t, "prod", ps.Spec.Label)
+	assert.Equal(t, KeyMappingLastSegment, ps.Spec.KeyMapping)
+	assert.Equal(t, &metav1.Duration{Duration: 5 * time.Minute}, ps.Spec.RefreshInterval)
+
+	// Fields set on the ParameterStore are kept, the namespace of the request is
+	// used if the object has none.
+	ps = &ParameterStore{Spec: ParameterStoreSpec{Label: "dev", KeyMapping: KeyMappingFullKey}}
+	ctx := admission.NewContextWithRequest(context.TODO(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Namespace: "team"}})
+	assert.Nil(t, defaulter.Default(ctx, ps))
+	assert.Equal(t, "team-store", ps.Spec.Store)
+	assert.Equal(t, "dev", ps.Spec.Label)
+	assert.Equal(t, KeyMappingFullKey, ps.Spec.KeyMapping)
+
+	ps = &ParameterStore{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "missing"}}
+	assert.NotNil(t, defaulter.Default(context.TODO(), ps))
+}
+
+func TestDefaultInvalidAnnotations(t *testing.T) {
+	spec := &ParameterStoreSpec{}
+	assert.ErrorContains(t, spec.Default(map[string]string{DefaultKeyMappingAnnotation: "camelCase"}), DefaultKeyMappingAnnotation)
+	assert.ErrorContains(t, spec.Default(map[string]string{DefaultRefreshIntervalAnnotation: "daily"}), DefaultRefreshIntervalAnnotation)
+	assert.ErrorContains(t, spec.Default(map[string]string{DefaultStoreAnnotation: "evil.example/x?"}), DefaultStoreAnnotation)
+	assert.Equal(t, ParameterStoreSpec{}, *spec)
+}
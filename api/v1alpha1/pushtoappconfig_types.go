@@ -0,0 +1,115 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	ConditionTypeSecretMissing string = "SecretMissing"
+	ConditionTypePushConflict  string = "PushConflict"
+	ConditionTypePushError     string = "PushError"
+
+	ConflictPolicyFail      string = "Fail"
+	ConflictPolicyOverwrite string = "Overwrite"
+
+	DeletionPolicyRetain string = "Retain"
+	DeletionPolicyDelete string = "Delete"
+)
+
+// PushToAppConfigSpec defines the desired state of PushToAppConfig
+type PushToAppConfigSpec struct {
+	// SecretRef is the Secret in the same namespace whose keys are pushed.
+	SecretRef SecretReference `json:"secretRef"`
+	// Data selects the keys of the Secret that are pushed.
+	Data []PushData `json:"data"`
+	// KeyTemplate is a Go template rendering the App Configuration key of a Secret key
+	// that has no explicit key. Available fields are .Namespace, .SecretName and .SecretKey.
+	// +kubebuilder:default:="/{{ .Namespace }}/{{ .SecretName }}/{{ .SecretKey }}"
+	KeyTemplate string `json:"keyTemplate,omitempty"`
+	// LabelTemplate is a Go template rendering the label of the App Configuration settings.
+	// Available fields are .Namespace, .SecretName and .SecretKey.
+	// +kubebuilder:validation:Optional
+	LabelTemplate string `json:"labelTemplate,omitempty"`
+	// KeyVault stores the values in an Azure Key Vault and pushes Key Vault references
+	// to App Configuration instead of the plain values.
+	// +kubebuilder:validation:Optional
+	KeyVault *KeyVaultRef `json:"keyVault,omitempty"`
+	// ConflictPolicy decides what happens if a setting was changed by someone else
+	// since it was pushed the last time or existed before it was pushed the first time.
+	// +kubebuilder:validation:Enum=Fail;Overwrite
+	// +kubebuilder:default:=Fail
+	ConflictPolicy string `json:"conflictPolicy,omitempty"`
+	// DeletionPolicy decides if the pushed settings are deleted together with the PushToAppConfig.
+	// +kubebuilder:validation:Enum=Retain;Delete
+	// +kubebuilder:default:=Retain
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+}
+
+type SecretReference struct {
+	Name string `json:"name"`
+}
+
+type PushData struct {
+	SecretKey string `json:"secretKey"`
+	// Key is the App Configuration key, it overrides the KeyTemplate.
+	// +kubebuilder:validation:Optional
+	Key string `json:"key,omitempty"`
+}
+
+type KeyVaultRef struct {
+	// VaultURL is the URL of the Key Vault e.g. https://my-vault.vault.azure.net
+	// The operator only writes to the Key Vaults with the DNS suffixes it is configured with.
+	// +kubebuilder:validation:Pattern=`^https://[a-zA-Z0-9-]{3,24}\.vault\.(azure\.net|azure\.cn|usgovcloudapi\.net|microsoftazure\.de)/?$`
+	VaultURL string `json:"vaultURL"`
+}
+
+// PushToAppConfigStatus defines the observed state of PushToAppConfig
+type PushToAppConfigStatus struct {
+	Keys       []PushedKeyStatus  `json:"keys,omitempty"`
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+type PushedKeyStatus struct {
+	SecretKey string `json:"secretKey"`
+	Key       string `json:"key"`
+	Label     string `json:"label,omitempty"`
+	ETag      string `json:"etag,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// PushToAppConfig is the Schema for the pushtoappconfigs API
+type PushToAppConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PushToAppConfigSpec   `json:"spec,omitempty"`
+	Status PushToAppConfigStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// PushToAppConfigList contains a list of PushToAppConfig
+type PushToAppConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PushToAppConfig `json:"items"`
+}
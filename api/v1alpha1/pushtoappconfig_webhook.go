@@ -0,0 +1,133 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"bytes"
+	"context"
+	"text/template"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// KeyVaultRefContentType is the content type of App Configuration settings referencing a Key Vault secret.
+const KeyVaultRefContentType = "application/vnd.microsoft.appconfig.keyvaultref+json;charset=utf-8"
+
+// SetupWebhookWithManager registers the validating webhook of the PushToAppConfig.
+func (r *PushToAppConfig) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, r).
+		WithValidator(&pushToAppConfigValidator{reader: mgr.GetAPIReader()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-ssm-aws-v1alpha1-pushtoappconfig,mutating=false,failurePolicy=fail,sideEffects=None,groups=ssm.aws,resources=pushtoappconfigs,verbs=create;update,versions=v1alpha1,name=vpushtoappconfig.kb.io,admissionReviewVersions=v1
+
+// pushToAppConfigValidator rejects PushToAppConfigs writing settings the
+// AppConfigAccessPolicies of the namespace don't allow before they are stored.
+type pushToAppConfigValidator struct {
+	reader client.Reader
+}
+
+func (v *pushToAppConfigValidator) ValidateCreate(ctx context.Context, obj *PushToAppConfig) (admission.Warnings, error) {
+	return nil, CheckPushAccess(ctx, v.reader, obj)
+}
+
+func (v *pushToAppConfigValidator) ValidateUpdate(ctx context.Context, oldObj, newObj *PushToAppConfig) (admission.Warnings, error) {
+	return nil, CheckPushAccess(ctx, v.reader, newObj)
+}
+
+func (v *pushToAppConfigValidator) ValidateDelete(ctx context.Context, obj *PushToAppConfig) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// CheckPushAccess returns a Forbidden error if the AppConfigAccessPolicies of
+// the namespace don't allow to write the settings of the PushToAppConfig. Keys
+// whose templates fail are left to the operator to report.
+func CheckPushAccess(ctx context.Context, reader client.Reader, cr *PushToAppConfig) error {
+	namespace := requestNamespace(ctx, cr.Namespace)
+	policies, err := AccessPoliciesFor(ctx, reader, namespace)
+	if err != nil {
+		return err
+	}
+	keys := make([]PushedKeyStatus, 0, len(cr.Spec.Data))
+	for _, data := range cr.Spec.Data {
+		ks := PushedKeyStatus{SecretKey: data.SecretKey}
+		if ks.Key, ks.Label, err = cr.settingKey(namespace, data); err == nil {
+			keys = append(keys, ks)
+		}
+	}
+	if err := policies.CheckPush(keys, cr.ContentType()); err != nil {
+		return apierrors.NewForbidden(GroupVersion.WithResource("pushtoappconfigs").GroupResource(), cr.Name, err)
+	}
+	return nil
+}
+
+// ContentType returns the content type of the pushed settings.
+func (r *PushToAppConfig) ContentType() string {
+	if r.Spec.KeyVault != nil {
+		return KeyVaultRefContentType
+	}
+	return ""
+}
+
+// templateData are the fields available in the key and label templates.
+type templateData struct {
+	Namespace  string
+	SecretName string
+	SecretKey  string
+}
+
+// SettingKey returns the App Configuration key and label of the pushed Secret key.
+func (r *PushToAppConfig) SettingKey(data PushData) (string, string, error) {
+	return r.settingKey(r.Namespace, data)
+}
+
+func (r *PushToAppConfig) settingKey(namespace string, data PushData) (string, string, error) {
+	values := templateData{
+		Namespace:  namespace,
+		SecretName: r.Spec.SecretRef.Name,
+		SecretKey:  data.SecretKey,
+	}
+	key := data.Key
+	if key == "" {
+		var err error
+		key, err = render("key", r.Spec.KeyTemplate, values)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	label, err := render("label", r.Spec.LabelTemplate, values)
+	if err != nil {
+		return "", "", err
+	}
+	return key, label, nil
+}
+
+func render(name, text string, values templateData) (string, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, values); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,73 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func testPushToAppConfig() *PushToAppConfig {
+	return &PushToAppConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "push", Namespace: "payments"},
+		Spec: PushToAppConfigSpec{
+			SecretRef: SecretReference{Name: "database"},
+			Data: []PushData{
+				{SecretKey: "user"},
+				{SecretKey: "password", Key: "/shared/dbpassword"},
+			},
+			KeyTemplate:   "/prod/{{ .Namespace }}/{{ .SecretName }}/{{ .SecretKey }}",
+			LabelTemplate: "prod",
+		},
+	}
+}
+
+func TestSettingKey(t *testing.T) {
+	cr := testPushToAppConfig()
+
+	key, label, err := cr.SettingKey(PushData{SecretKey: "user"})
+	assert.Nil(t, err)
+	assert.Equal(t, "/prod/payments/database/user", key)
+	assert.Equal(t, "prod", label)
+
+	cr.Spec.KeyTemplate = "{{ .Unknown }}"
+	_, _, err = cr.SettingKey(PushData{SecretKey: "user"})
+	assert.NotNil(t, err)
+}
+
+func TestCheckPushAccess(t *testing.T) {
+	policy := paymentsPolicy()
+	policy.Spec.Rules = []AccessRule{{KeyPrefixes: []string{"/prod/payments/"}, Write: true}, {KeyPrefixes: []string{"/shared/"}}}
+	reader := testReader(t,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments", Labels: map[string]string{"team": "payments"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+		policy)
+	validator := &pushToAppConfigValidator{reader: reader}
+
+	_, err := validator.ValidateCreate(context.TODO(), testPushToAppConfig())
+	assert.True(t, apierrors.IsForbidden(err))
+	assert.Contains(t, err.Error(), "don't allow to write /shared/dbpassword")
+
+	cr := testPushToAppConfig()
+	cr.Spec.Data = cr.Spec.Data[:1]
+	_, err = validator.ValidateCreate(context.TODO(), cr)
+	assert.Nil(t, err)
+
+	// The content type of Key Vault references is checked too.
+	policy.Spec.Rules[0].ContentTypes = []string{""}
+	assert.Nil(t, reader.(client.Client).Update(context.TODO(), policy))
+	cr.Spec.KeyVault = &KeyVaultRef{VaultURL: "https://payments.vault.azure.net"}
+	_, err = validator.ValidateUpdate(context.TODO(), cr, cr)
+	assert.True(t, apierrors.IsForbidden(err))
+
+	// Namespaces no policy selects may not push anything.
+	cr = testPushToAppConfig()
+	cr.Namespace = "other"
+	cr.Spec.Data = cr.Spec.Data[:1]
+	_, err = validator.ValidateUpdate(context.TODO(), cr, cr)
+	assert.True(t, apierrors.IsForbidden(err))
+}
@@ -14,20 +14,20 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-// Package v1alpha1 contains API Schema definitions for the ssm v1alpha1 API group
-//+kubebuilder:object:generate=true
-//+groupName=ssm.aws
-package v1alpha1
+// Package v1beta1 contains API Schema definitions for the ssm v1beta1 API group
+// +kubebuilder:object:generate=true
+// +groupName=ssm.aws
+package v1beta1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var (
 	// GroupVersion is group version used to register these objects
-	GroupVersion = schema.GroupVersion{Group: "ssm.aws", Version: "v1alpha1"}
+	GroupVersion = schema.GroupVersion{Group: "ssm.aws", Version: "v1beta1"}
 
 	// SchemeBuilder is used to add go types to the GroupVersionKind scheme
 	SchemeBuilder = runtime.NewSchemeBuilder(addKnownTypes)
@@ -0,0 +1,200 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+)
+
+// ParameterStoreRefAnnotation marks the first source as the parameterStoreRef
+// name of v1alpha1. It reads the setting on its own and fails the whole sync if
+// the setting can't be read, a source with key and secretKey can't tell apart.
+const ParameterStoreRefAnnotation = "ssm.aws/v1alpha1-parameter-store-ref"
+
+// NonRecursiveAnnotation keeps the recursive: false of the parameterStoreRef of
+// v1alpha1, sources have no such field.
+const NonRecursiveAnnotation = "ssm.aws/v1alpha1-non-recursive"
+
+// ConvertTo converts the ParameterStore to the v1alpha1 hub.
+func (src *ParameterStore) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.ParameterStore)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	delete(dst.Annotations, ParameterStoreRefAnnotation)
+	delete(dst.Annotations, NonRecursiveAnnotation)
+	if len(dst.Annotations) == 0 {
+		dst.Annotations = nil
+	}
+
+	spec := &dst.Spec
+	sources := src.Spec.Sources
+	_, nonRecursive := src.Annotations[NonRecursiveAnnotation]
+	if first, ok := src.firstSourceIsNameRef(); ok {
+		spec.ValueFrom.ParameterStoreRef = &v1alpha1.ParameterStoreRef{Name: first.Key, Recursive: !nonRecursive}
+		sources = sources[1:]
+	}
+	for i, s := range sources {
+		if s.Path == "" {
+			spec.ValueFrom.ParametersStoreRef = append(spec.ValueFrom.ParametersStoreRef, v1alpha1.ParametersStoreRef{
+				Name:     s.SecretKey,
+				Key:      s.Key,
+				Optional: s.Optional,
+				Default:  s.Default,
+			})
+			continue
+		}
+		if i > 0 || spec.ValueFrom.ParameterStoreRef != nil {
+			return fmt.Errorf("source %s: only the first source may select a path", s.Path)
+		}
+		spec.ValueFrom.ParameterStoreRef = &v1alpha1.ParameterStoreRef{Path: s.Path, Recursive: !nonRecursive}
+	}
+
+	spec.Store = src.Spec.StoreRef.Name
+	spec.Label = src.Spec.StoreRef.Label
+	spec.KeyMapping = src.Spec.Target.KeyMapping
+	for _, w := range src.Spec.Target.Rollout.Workloads {
+		spec.RolloutTargets = append(spec.RolloutTargets, v1alpha1.RolloutTarget{Kind: w.Kind, Name: w.Name})
+	}
+	spec.AutoRollout = src.Spec.Target.Rollout.Auto
+	spec.DeleteStaleSecret = src.Spec.Target.DeleteWhenDegraded
+	spec.FailurePolicy = src.Spec.FailurePolicy
+	spec.MaxStaleness = src.Spec.MaxStaleness
+	spec.RefreshInterval = src.Spec.RefreshInterval
+	spec.DryRun = src.Spec.DryRun
+
+	status := &dst.Status
+	if s := src.Status.Secret; s != nil {
+		status.SecretStatus = &v1alpha1.SecretStatus{Name: s.Name, Namespace: s.Namespace}
+	}
+	if s := src.Status.Source; s != nil {
+		status.SSMStatus = &v1alpha1.SSMStatus{Error: s.Error}
+		for _, k := range s.Keys {
+			status.SSMStatus.Key = append(status.SSMStatus.Key, v1alpha1.KeyStatus(k))
+		}
+	}
+	status.Conditions = src.Status.Conditions
+	status.ObservedGeneration = src.Status.ObservedGeneration
+	status.SyncedKeys = src.Status.SyncedKeys
+	status.LastSyncTime = src.Status.LastSyncTime
+	if s := src.Status.DryRun; s != nil {
+		status.DryRun = &v1alpha1.DryRunStatus{Added: s.Added, Removed: s.Removed, Changed: s.Changed, Error: s.Error, Time: s.Time}
+		for _, k := range s.Keys {
+			status.DryRun.Keys = append(status.DryRun.Keys, v1alpha1.KeyStatus(k))
+		}
+	}
+	if s := src.Status.LastChange; s != nil {
+		status.LastChange = &v1alpha1.SecretChangeStatus{Added: s.Added, Removed: s.Removed, Changed: s.Changed, Time: s.Time}
+		for _, c := range s.Sources {
+			status.LastChange.Sources = append(status.LastChange.Sources, v1alpha1.ChangeSource(c))
+		}
+	}
+	return nil
+}
+
+// firstSourceIsNameRef returns the first source if it was converted from the
+// parameterStoreRef name of v1alpha1 and wasn't changed since.
+func (src *ParameterStore) firstSourceIsNameRef() (Source, bool) {
+	if _, ok := src.Annotations[ParameterStoreRefAnnotation]; !ok || len(src.Spec.Sources) == 0 {
+		return Source{}, false
+	}
+	first := src.Spec.Sources[0]
+	return first, first.Key != "" && first.SecretKey == first.Key && !first.Optional && first.Default == nil
+}
+
+func (dst *ParameterStore) annotate(key, value string) {
+	if dst.Annotations == nil {
+		dst.Annotations = map[string]string{}
+	}
+	dst.Annotations[key] = value
+}
+
+// ConvertFrom converts the v1alpha1 hub to the ParameterStore.
+func (dst *ParameterStore) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.ParameterStore)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	delete(dst.Annotations, ParameterStoreRefAnnotation)
+	delete(dst.Annotations, NonRecursiveAnnotation)
+
+	spec := &dst.Spec
+	valueFrom := src.Spec.ValueFrom
+	if ref := valueFrom.ParameterStoreRef; ref != nil {
+		switch {
+		case ref.Name != "":
+			spec.Sources = append(spec.Sources, Source{Key: ref.Name, SecretKey: ref.Name})
+			dst.annotate(ParameterStoreRefAnnotation, "name")
+		case ref.Path != "":
+			spec.Sources = append(spec.Sources, Source{Path: ref.Path})
+		}
+		if !ref.Recursive {
+			dst.annotate(NonRecursiveAnnotation, "true")
+		}
+	}
+	for _, ref := range valueFrom.ParametersStoreRef {
+		spec.Sources = append(spec.Sources, Source{
+			Key:       ref.Key,
+			SecretKey: ref.Name,
+			Optional:  ref.Optional,
+			Default:   ref.Default,
+		})
+	}
+	if len(dst.Annotations) == 0 {
+		dst.Annotations = nil
+	}
+
+	spec.StoreRef = StoreRef{Name: src.Spec.Store, Label: src.Spec.Label}
+	spec.Target.KeyMapping = src.Spec.KeyMapping
+	for _, t := range src.Spec.RolloutTargets {
+		spec.Target.Rollout.Workloads = append(spec.Target.Rollout.Workloads, Workload{Kind: t.Kind, Name: t.Name})
+	}
+	spec.Target.Rollout.Auto = src.Spec.AutoRollout
+	spec.Target.DeleteWhenDegraded = src.Spec.DeleteStaleSecret
+	spec.FailurePolicy = src.Spec.FailurePolicy
+	spec.MaxStaleness = src.Spec.MaxStaleness
+	spec.RefreshInterval = src.Spec.RefreshInterval
+	spec.DryRun = src.Spec.DryRun
+
+	status := &dst.Status
+	if s := src.Status.SecretStatus; s != nil {
+		status.Secret = &SecretStatus{Name: s.Name, Namespace: s.Namespace}
+	}
+	if s := src.Status.SSMStatus; s != nil {
+		status.Source = &SourceStatus{Error: s.Error}
+		for _, k := range s.Key {
+			status.Source.Keys = append(status.Source.Keys, KeyStatus(k))
+		}
+	}
+	status.Conditions = src.Status.Conditions
+	status.ObservedGeneration = src.Status.ObservedGeneration
+	status.SyncedKeys = src.Status.SyncedKeys
+	status.LastSyncTime = src.Status.LastSyncTime
+	if s := src.Status.DryRun; s != nil {
+		status.DryRun = &DryRunStatus{Added: s.Added, Removed: s.Removed, Changed: s.Changed, Error: s.Error, Time: s.Time}
+		for _, k := range s.Keys {
+			status.DryRun.Keys = append(status.DryRun.Keys, KeyStatus(k))
+		}
+	}
+	if s := src.Status.LastChange; s != nil {
+		status.LastChange = &SecretChangeStatus{Added: s.Added, Removed: s.Removed, Changed: s.Changed, Time: s.Time}
+		for _, c := range s.Sources {
+			status.LastChange.Sources = append(status.LastChange.Sources, ChangeSource(c))
+		}
+	}
+	return nil
+}
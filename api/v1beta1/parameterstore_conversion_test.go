@@ -0,0 +1,201 @@
+package v1beta1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+)
+
+func ptr(s string) *string {
+	return &s
+}
+
+func alphaStatus() v1alpha1.ParameterStoreStatus {
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	return v1alpha1.ParameterStoreStatus{
+		SecretStatus: &v1alpha1.SecretStatus{Name: "app", Namespace: "default"},
+		SSMStatus: &v1alpha1.SSMStatus{Key: []v1alpha1.KeyStatus{
+			{Name: "USER", State: v1alpha1.KeyStateSynced, Key: "/app/user", ETag: "etag", LastModified: &now},
+		}},
+		Conditions:         []metav1.Condition{{Type: v1alpha1.ConditionTypeReady, Status: metav1.ConditionTrue, Reason: v1alpha1.ReconciliationSucceededReason}},
+		ObservedGeneration: 2,
+		SyncedKeys:         1,
+		LastSyncTime:       &now,
+		DryRun: &v1alpha1.DryRunStatus{
+			Added:   []string{"PASSWORD"},
+			Removed: []string{"HOST"},
+			Changed: []string{"USER"},
+			Keys:    []v1alpha1.KeyStatus{{Name: "PASSWORD", State: v1alpha1.KeyStateSynced, Key: "/app/password", ETag: "etag"}},
+			Time:    now,
+		},
+		LastChange: &v1alpha1.SecretChangeStatus{
+			Changed: []string{"USER"},
+			Sources: []v1alpha1.ChangeSource{{Name: "USER", Key: "/app/user", Label: "prod", ETag: "etag"}},
+			Time:    now,
+		},
+	}
+}
+
+func TestConvertRoundTripFromHub(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		hub  v1alpha1.ParameterStore
+	}{
+		{
+			name: "path",
+			hub: v1alpha1.ParameterStore{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", Annotations: map[string]string{"team": "a"}},
+				Spec: v1alpha1.ParameterStoreSpec{
+					ValueFrom: v1alpha1.ValueFrom{
+						ParameterStoreRef:  &v1alpha1.ParameterStoreRef{Path: "/app/", Recursive: true},
+						ParametersStoreRef: []v1alpha1.ParametersStoreRef{{Name: "DB_USER", Key: "/db/user", Optional: true}, {Key: "/db/password", Default: ptr("secret")}},
+					},
+					RolloutTargets:    []v1alpha1.RolloutTarget{{Kind: "Deployment", Name: "app"}},
+					AutoRollout:       true,
+					FailurePolicy:     v1alpha1.FailurePolicyKeepLastKnown,
+					MaxStaleness:      &metav1.Duration{Duration: time.Hour},
+					DeleteStaleSecret: true,
+					Store:             "team",
+					Label:             "prod",
+					KeyMapping:        v1alpha1.KeyMappingLastSegment,
+					RefreshInterval:   &metav1.Duration{Duration: time.Minute},
+					DryRun:            true,
+				},
+				Status: alphaStatus(),
+			},
+		},
+		{
+			name: "name",
+			hub: v1alpha1.ParameterStore{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+				Spec: v1alpha1.ParameterStoreSpec{
+					ValueFrom: v1alpha1.ValueFrom{
+						ParameterStoreRef:  &v1alpha1.ParameterStoreRef{Name: "/app/user", Recursive: true},
+						ParametersStoreRef: []v1alpha1.ParametersStoreRef{{Name: "/app/user", Key: "/app/user"}},
+					},
+				},
+			},
+		},
+		{
+			name: "path not recursive",
+			hub: v1alpha1.ParameterStore{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+				Spec:       v1alpha1.ParameterStoreSpec{ValueFrom: v1alpha1.ValueFrom{ParameterStoreRef: &v1alpha1.ParameterStoreRef{Path: "/app/"}}},
+			},
+		},
+		{
+			name: "name not recursive",
+			hub: v1alpha1.ParameterStore{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+				Spec:       v1alpha1.ParameterStoreSpec{ValueFrom: v1alpha1.ValueFrom{ParameterStoreRef: &v1alpha1.ParameterStoreRef{Name: "/app/user"}}},
+			},
+		},
+		{
+			name: "empty",
+			hub:  v1alpha1.ParameterStore{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			spoke := &ParameterStore{}
+			assert.Nil(t, spoke.ConvertFrom(&tc.hub))
+			hub := &v1alpha1.ParameterStore{}
+			assert.Nil(t, spoke.ConvertTo(hub))
+			assert.Equal(t, tc.hub, *hub)
+		})
+	}
+}
+
+func TestConvertRoundTripFromSpoke(t *testing.T) {
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	for _, tc := range []struct {
+		name  string
+		spoke ParameterStore
+	}{
+		{
+			name: "full",
+			spoke: ParameterStore{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+				Spec: ParameterStoreSpec{
+					StoreRef: StoreRef{Name: "team", Label: "prod"},
+					Sources:  []Source{{Path: "/app/"}, {Key: "/db/user", SecretKey: "DB_USER"}, {Key: "/db/password", Optional: true, Default: ptr("secret")}},
+					Target: Target{
+						KeyMapping:         v1alpha1.KeyMappingFullKey,
+						Rollout:            Rollout{Workloads: []Workload{{Kind: "StatefulSet", Name: "db"}}, Auto: true},
+						DeleteWhenDegraded: true,
+					},
+					FailurePolicy:   v1alpha1.FailurePolicyPartial,
+					MaxStaleness:    &metav1.Duration{Duration: time.Hour},
+					RefreshInterval: &metav1.Duration{Duration: time.Minute},
+					DryRun:          true,
+				},
+				Status: ParameterStoreStatus{
+					Secret:       &SecretStatus{Name: "app", Namespace: "default"},
+					Source:       &SourceStatus{Error: "throttled", Keys: []KeyStatus{{Name: "DB_USER", State: v1alpha1.KeyStateFailed, Error: "throttled"}}},
+					LastSyncTime: &now,
+					DryRun:       &DryRunStatus{Error: "throttled", Time: now},
+					LastChange:   &SecretChangeStatus{Removed: []string{"DB_HOST"}, Sources: []ChangeSource{{Name: "DB_HOST", Key: "/db/host"}}, Time: now},
+				},
+			},
+		},
+		{
+			name: "name ref",
+			spoke: ParameterStore{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", Annotations: map[string]string{ParameterStoreRefAnnotation: "name"}},
+				Spec:       ParameterStoreSpec{Sources: []Source{{Key: "/app/user", SecretKey: "/app/user"}, {Key: "/app/user", SecretKey: "/app/user"}}},
+			},
+		},
+		{
+			name: "empty source status",
+			spoke: ParameterStore{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+				Status:     ParameterStoreStatus{Source: &SourceStatus{}},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hub := &v1alpha1.ParameterStore{}
+			assert.Nil(t, tc.spoke.ConvertTo(hub))
+			spoke := &ParameterStore{}
+			assert.Nil(t, spoke.ConvertFrom(hub))
+			assert.Equal(t, tc.spoke, *spoke)
+		})
+	}
+}
+
+func TestConvertNameRef(t *testing.T) {
+	hub := &v1alpha1.ParameterStore{Spec: v1alpha1.ParameterStoreSpec{ValueFrom: v1alpha1.ValueFrom{
+		ParameterStoreRef: &v1alpha1.ParameterStoreRef{Name: "/app/user", Recursive: true},
+	}}}
+	spoke := &ParameterStore{}
+	assert.Nil(t, spoke.ConvertFrom(hub))
+	assert.Equal(t, []Source{{Key: "/app/user", SecretKey: "/app/user"}}, spoke.Spec.Sources)
+	assert.Equal(t, "name", spoke.Annotations[ParameterStoreRefAnnotation])
+
+	// Once the source was changed, it is a key like any other.
+	spoke.Spec.Sources[0].SecretKey = "USER"
+	hub = &v1alpha1.ParameterStore{}
+	assert.Nil(t, spoke.ConvertTo(hub))
+	assert.Nil(t, hub.Spec.ValueFrom.ParameterStoreRef)
+	assert.Equal(t, []v1alpha1.ParametersStoreRef{{Name: "USER", Key: "/app/user"}}, hub.Spec.ValueFrom.ParametersStoreRef)
+	assert.Nil(t, hub.Annotations)
+}
+
+func TestConvertPathNotFirst(t *testing.T) {
+	spoke := &ParameterStore{Spec: ParameterStoreSpec{Sources: []Source{{Key: "/app/user"}, {Path: "/app/"}}}}
+	assert.ErrorContains(t, spoke.ConvertTo(&v1alpha1.ParameterStore{}), "only the first source may select a path")
+}
+
+func TestIsConvertible(t *testing.T) {
+	s := runtime.NewScheme()
+	assert.Nil(t, v1alpha1.AddToScheme(s))
+	assert.Nil(t, AddToScheme(s))
+	ok, err := conversion.IsConvertible(s, &ParameterStore{})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+}
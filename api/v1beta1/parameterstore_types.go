@@ -0,0 +1,233 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ParameterStoreSpec defines the desired state of ParameterStore
+type ParameterStoreSpec struct {
+	// StoreRef selects the App Configuration store and the label of the settings.
+	// +kubebuilder:validation:Optional
+	StoreRef StoreRef `json:"storeRef,omitempty"`
+	// Sources are the settings synced to the Secret. Only the first source may
+	// select a path, the keys of the following sources overwrite the keys read by path.
+	// +kubebuilder:validation:Optional
+	Sources []Source `json:"sources,omitempty"`
+	// Target describes the keys of the Secret and the workloads restarted when it changes.
+	// +kubebuilder:validation:Optional
+	Target Target `json:"target,omitempty"`
+	// FailurePolicy decides what happens if keys of the sources can't be fetched.
+	// Fail doesn't write the Secret, Partial writes it without the failed keys and
+	// KeepLastKnown writes it with the values the failed keys had in the Secret before.
+	// +kubebuilder:validation:Enum=Fail;Partial;KeepLastKnown
+	// +kubebuilder:default:=Fail
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+	// MaxStaleness is how long the Secret may keep the data of the last successful
+	// sync while syncing fails, before the ParameterStore is Degraded.
+	// +kubebuilder:validation:Optional
+	MaxStaleness *metav1.Duration `json:"maxStaleness,omitempty"`
+	// RefreshInterval is the interval the values are synced in, even if neither
+	// the ParameterStore nor the Secret changed.
+	// +kubebuilder:validation:Optional
+	RefreshInterval *metav1.Duration `json:"refreshInterval,omitempty"`
+	// DryRun fetches and maps the settings without writing the Secret, the keys
+	// the sync would add, remove or change are written to the status instead.
+	// +kubebuilder:validation:Optional
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// StoreRef selects the App Configuration store and the label of the settings.
+type StoreRef struct {
+	// Name is the name of the App Configuration store, the store of the operator if empty.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9-]{5,50}$`
+	Name string `json:"name,omitempty"`
+	// Label selects the settings with the label. If empty, keys are read without
+	// label and paths with any label.
+	// +kubebuilder:validation:Optional
+	Label string `json:"label,omitempty"`
+}
+
+// Source is a single setting or all settings whose key starts with a path.
+// +kubebuilder:validation:XValidation:rule="(has(self.key) && self.key != '') != (has(self.path) && self.path != '')",message="exactly one of key or path must be set"
+type Source struct {
+	// Key is the key of a single setting.
+	// +kubebuilder:validation:Optional
+	Key string `json:"key,omitempty"`
+	// Path selects all settings whose key starts with the path.
+	// +kubebuilder:validation:Optional
+	Path string `json:"path,omitempty"`
+	// SecretKey is the key of the single setting in the Secret, derived by the
+	// key mapping of the target if empty.
+	// +kubebuilder:validation:Optional
+	SecretKey string `json:"secretKey,omitempty"`
+	// Optional keys that don't exist are left out of the Secret instead of failing the sync.
+	// +kubebuilder:validation:Optional
+	Optional bool `json:"optional,omitempty"`
+	// Default is the value used if the key doesn't exist, it makes the key optional.
+	// +kubebuilder:validation:Optional
+	Default *string `json:"default,omitempty"`
+}
+
+// Target describes the keys of the Secret and the workloads restarted when it changes.
+type Target struct {
+	// KeyMapping derives the keys of the Secret from the keys of the settings
+	// without secretKey. UpperSnakeCase upper-cases the last segment of the key
+	// and replaces - by _, LastSegment keeps the last segment and FullKey
+	// replaces the / of the whole key by _. UpperSnakeCase if empty.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=UpperSnakeCase;LastSegment;FullKey
+	KeyMapping string `json:"keyMapping,omitempty"`
+	// Rollout restarts workloads when the data of the Secret changes.
+	// +kubebuilder:validation:Optional
+	Rollout Rollout `json:"rollout,omitempty"`
+	// DeleteWhenDegraded removes the Secret, or the keys written by the operator
+	// of a shared Secret, once the ParameterStore is Degraded.
+	// +kubebuilder:validation:Optional
+	DeleteWhenDegraded bool `json:"deleteWhenDegraded,omitempty"`
+}
+
+// Rollout restarts workloads when the data of the Secret changes.
+type Rollout struct {
+	// Workloads are restarted when the data of the Secret changes.
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=kind
+	// +listMapKey=name
+	Workloads []Workload `json:"workloads,omitempty"`
+	// Auto restarts all Deployments, StatefulSets and DaemonSets in the namespace
+	// that reference the Secret via env, envFrom or volumes.
+	// +kubebuilder:validation:Optional
+	Auto bool `json:"auto,omitempty"`
+}
+
+type Workload struct {
+	// +kubebuilder:validation:Enum=Deployment;StatefulSet;DaemonSet
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// ParameterStoreStatus defines the observed state of ParameterStore
+type ParameterStoreStatus struct {
+	Secret *SecretStatus `json:"secret,omitempty"`
+	// Source is the result of reading the sources of the last sync.
+	Source     *SourceStatus      `json:"source,omitempty"`
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation of the spec the status was computed for.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// SyncedKeys is the number of keys written to the Secret by the last sync.
+	SyncedKeys int32 `json:"syncedKeys,omitempty"`
+	// LastSyncTime is the time of the last successful sync.
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// DryRun is the effect the last sync in dry-run mode would have on the Secret.
+	DryRun *DryRunStatus `json:"dryRun,omitempty"`
+	// LastChange is the change of the data of the Secret by the last sync that changed it.
+	LastChange *SecretChangeStatus `json:"lastChange,omitempty"`
+}
+
+type SecretStatus struct {
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// DryRunStatus lists the keys of the Secret a sync would add, remove or change,
+// never their values.
+type DryRunStatus struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	// Error is the error reading the settings, if the dry-run failed.
+	Error string `json:"error,omitempty"`
+	// Keys are the settings the keys would be synced from, or the errors
+	// fetching them. The keys of the last sync are kept in the status.
+	Keys []KeyStatus `json:"keys,omitempty"`
+	// Time is the time the result of the dry-run last changed.
+	Time metav1.Time `json:"time"`
+}
+
+// SecretChangeStatus lists the keys of the Secret a sync added, removed or
+// changed and the settings responsible, never their values.
+type SecretChangeStatus struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	// Sources are the settings the added and changed keys were synced from,
+	// and the removed keys were synced from before.
+	Sources []ChangeSource `json:"sources,omitempty"`
+	// Time is the time of the change.
+	Time metav1.Time `json:"time"`
+}
+
+// ChangeSource is the App Configuration setting of a key of the Secret.
+type ChangeSource struct {
+	// Name is the key in the Secret.
+	Name  string `json:"name"`
+	Key   string `json:"key,omitempty"`
+	Label string `json:"label,omitempty"`
+	ETag  string `json:"etag,omitempty"`
+}
+
+// SourceStatus is the error reading the sources or the state of their keys.
+type SourceStatus struct {
+	Error string      `json:"error,omitempty"`
+	Keys  []KeyStatus `json:"keys,omitempty"`
+}
+
+// KeyStatus is the state of a key of the Secret, either the App Configuration
+// setting it was synced from or the error fetching it.
+type KeyStatus struct {
+	// Name is the key in the Secret.
+	Name  string `json:"name,omitempty"`
+	Error string `json:"error,omitempty"`
+	// State is one of Synced, Defaulted, Missing, LastKnown or Failed.
+	State string `json:"state,omitempty"`
+	// Key is the App Configuration key the value was read from.
+	Key          string       `json:"key,omitempty"`
+	Label        string       `json:"label,omitempty"`
+	ETag         string       `json:"etag,omitempty"`
+	ContentType  string       `json:"contentType,omitempty"`
+	LastModified *metav1.Time `json:"lastModified,omitempty"`
+	Locked       bool         `json:"locked,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Keys",type=integer,JSONPath=`.status.syncedKeys`
+//+kubebuilder:printcolumn:name="LastSync",type=date,JSONPath=`.status.lastSyncTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ParameterStore is the Schema for the parameterstores API
+type ParameterStore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ParameterStoreSpec   `json:"spec,omitempty"`
+	Status ParameterStoreStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ParameterStoreList contains a list of ParameterStore
+type ParameterStoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ParameterStore `json:"items"`
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+)
+
+// SetupWebhookWithManager registers the defaulting, the validating and the
+// conversion webhook of the ParameterStore.
+func (r *ParameterStore) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, r).
+		WithDefaulter(&parameterStoreDefaulter{reader: mgr.GetAPIReader()}).
+		WithValidator(&parameterStoreValidator{reader: mgr.GetAPIReader()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-ssm-aws-v1beta1-parameterstore,mutating=true,failurePolicy=fail,sideEffects=None,groups=ssm.aws,resources=parameterstores,verbs=create;update,versions=v1beta1,name=mparameterstore-v1beta1.kb.io,admissionReviewVersions=v1
+
+// parameterStoreDefaulter fills the fields left empty from the annotations of
+// the Namespace with the defaulter of the v1alpha1 hub.
+type parameterStoreDefaulter struct {
+	reader client.Reader
+}
+
+func (d *parameterStoreDefaulter) Default(ctx context.Context, obj *ParameterStore) error {
+	hub := &v1alpha1.ParameterStore{}
+	if err := obj.ConvertTo(hub); err != nil {
+		// The sources can't be converted, the validator rejects them.
+		return nil
+	}
+	if err := v1alpha1.DefaultFromNamespace(ctx, d.reader, hub); err != nil {
+		return err
+	}
+	obj.Spec.StoreRef.Name = hub.Spec.Store
+	obj.Spec.StoreRef.Label = hub.Spec.Label
+	obj.Spec.Target.KeyMapping = hub.Spec.KeyMapping
+	obj.Spec.RefreshInterval = hub.Spec.RefreshInterval
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-ssm-aws-v1beta1-parameterstore,mutating=false,failurePolicy=fail,sideEffects=None,groups=ssm.aws,resources=parameterstores,verbs=create;update,versions=v1beta1,name=vparameterstore-v1beta1.kb.io,admissionReviewVersions=v1
+
+// parameterStoreValidator rejects specs that can't be synced, or read keys the
+// AppConfigAccessPolicies of the namespace don't allow, before they are stored.
+type parameterStoreValidator struct {
+	reader client.Reader
+}
+
+func (v *parameterStoreValidator) ValidateCreate(ctx context.Context, obj *ParameterStore) (admission.Warnings, error) {
+	if err := obj.Validate(); err != nil {
+		return nil, err
+	}
+	return nil, v.checkAccess(ctx, obj)
+}
+
+func (v *parameterStoreValidator) ValidateUpdate(ctx context.Context, oldObj, newObj *ParameterStore) (admission.Warnings, error) {
+	if err := newObj.Validate(); err != nil {
+		return nil, err
+	}
+	return nil, v.checkAccess(ctx, newObj)
+}
+
+// checkAccess checks the keys of the hub version against the AppConfigAccessPolicies.
+func (v *parameterStoreValidator) checkAccess(ctx context.Context, obj *ParameterStore) error {
+	hub := &v1alpha1.ParameterStore{}
+	if err := obj.ConvertTo(hub); err != nil {
+		return err
+	}
+	return v1alpha1.CheckAccess(ctx, v.reader, hub)
+}
+
+func (v *parameterStoreValidator) ValidateDelete(ctx context.Context, obj *ParameterStore) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// Validate returns the field errors of the spec as an Invalid error. Once the
+// sources are valid, the spec is validated by the v1alpha1 hub so both versions
+// enforce the same rules.
+func (r *ParameterStore) Validate() error {
+	errs := r.Spec.validateSources(field.NewPath("spec"))
+	if len(errs) == 0 {
+		hub := &v1alpha1.ParameterStore{}
+		if err := r.ConvertTo(hub); err != nil {
+			return err
+		}
+		for _, err := range hub.Spec.Validate(field.NewPath("spec")) {
+			err.Field = v1beta1Field(&hub.Spec, err.Field)
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("ParameterStore").GroupKind(), r.Name, errs)
+}
+
+// parametersStoreRefField matches the fields of the parametersStoreRef of the hub.
+var parametersStoreRefField = regexp.MustCompile(`^spec\.valueFrom\.parametersStoreRef\[(\d+)\](.*)$`)
+
+// v1beta1Field maps a field of the hub spec to the field it was converted from.
+func v1beta1Field(hub *v1alpha1.ParameterStoreSpec, f string) string {
+	switch {
+	case f == "spec.store":
+		return "spec.storeRef.name"
+	case strings.HasPrefix(f, "spec.valueFrom.parameterStoreRef"):
+		return "spec.sources[0]" + strings.TrimPrefix(f, "spec.valueFrom.parameterStoreRef")
+	case strings.HasPrefix(f, "spec.rolloutTargets"):
+		return "spec.target.rollout.workloads" + strings.TrimPrefix(f, "spec.rolloutTargets")
+	}
+	if m := parametersStoreRefField.FindStringSubmatch(f); m != nil {
+		i, _ := strconv.Atoi(m[1])
+		if hub.ValueFrom.ParameterStoreRef != nil {
+			i++
+		}
+		child := m[2]
+		if child == ".name" {
+			child = ".secretKey"
+		}
+		return fmt.Sprintf("spec.sources[%d]%s", i, child)
+	}
+	return f
+}
+
+// validateSources returns the errors of the sources that can't be converted
+// to the hub.
+func (s *ParameterStoreSpec) validateSources(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, src := range s.Sources {
+		srcPath := path.Child("sources").Index(i)
+		switch {
+		case src.Key == "" && src.Path == "":
+			errs = append(errs, field.Required(srcPath, "one of key or path must be set"))
+		case src.Key != "" && src.Path != "":
+			errs = append(errs, field.Invalid(srcPath.Child("path"), src.Path, "key and path are mutually exclusive"))
+		case src.Path != "" && i > 0:
+			errs = append(errs, field.Invalid(srcPath.Child("path"), src.Path, "only the first source may select a path"))
+		case src.Path != "" && (src.SecretKey != "" || src.Optional || src.Default != nil):
+			errs = append(errs, field.Invalid(srcPath.Child("path"), src.Path, "secretKey, optional and default are only supported with key"))
+		}
+	}
+	return errs
+}
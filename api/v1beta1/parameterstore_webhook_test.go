@@ -0,0 +1,149 @@
+package v1beta1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+)
+
+// testReader returns a fake reader knowing the AppConfigAccessPolicies.
+func testReader(t *testing.T, objs ...client.Object) client.Reader {
+	s := runtime.NewScheme()
+	assert.Nil(t, clientgoscheme.AddToScheme(s))
+	assert.Nil(t, v1alpha1.AddToScheme(s))
+	return fake.NewClientBuilder().WithScheme(s).WithObjects(objs...).Build()
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		spec   ParameterStoreSpec
+		fields []string
+	}{
+		{
+			name: "valid",
+			spec: ParameterStoreSpec{
+				Sources: []Source{{Path: "/app/"}, {Key: "user", SecretKey: "DB_USER"}, {Key: "db/password", Optional: true}},
+				Target:  Target{Rollout: Rollout{Workloads: []Workload{{Kind: "Deployment", Name: "app"}, {Kind: "StatefulSet", Name: "app"}}}},
+			},
+		},
+		{
+			name:   "neither key nor path",
+			spec:   ParameterStoreSpec{Sources: []Source{{}}},
+			fields: []string{"spec.sources[0]"},
+		},
+		{
+			name:   "key and path",
+			spec:   ParameterStoreSpec{Sources: []Source{{Key: "user", Path: "/app/"}}},
+			fields: []string{"spec.sources[0].path"},
+		},
+		{
+			name:   "path not first",
+			spec:   ParameterStoreSpec{Sources: []Source{{Key: "user"}, {Path: "/app/"}}},
+			fields: []string{"spec.sources[1].path"},
+		},
+		{
+			name:   "path with secret key",
+			spec:   ParameterStoreSpec{Sources: []Source{{Path: "/app/", SecretKey: "APP"}}},
+			fields: []string{"spec.sources[0].path"},
+		},
+		{
+			name: "duplicate secret keys",
+			spec: ParameterStoreSpec{
+				Sources: []Source{{Key: "app1/password", SecretKey: "password"}, {Key: "app2/password"}},
+				Target:  Target{KeyMapping: v1alpha1.KeyMappingLastSegment},
+			},
+			fields: []string{"spec.sources[1].secretKey"},
+		},
+		{
+			name: "duplicate secret keys after a path",
+			spec: ParameterStoreSpec{Sources: []Source{{Path: "/app/"}, {Key: "app1/password"}, {Key: "app2/password"}}},
+			// The rules of the hub are reported with the fields of v1beta1.
+			fields: []string{"spec.sources[2].secretKey"},
+		},
+		{
+			name:   "negative refresh interval",
+			spec:   ParameterStoreSpec{RefreshInterval: &metav1.Duration{Duration: -time.Minute}},
+			fields: []string{"spec.refreshInterval"},
+		},
+		{
+			name:   "store outside of the host name",
+			spec:   ParameterStoreSpec{StoreRef: StoreRef{Name: "evil.example/x?"}},
+			fields: []string{"spec.storeRef.name"},
+		},
+		{
+			name:   "duplicate workloads",
+			spec:   ParameterStoreSpec{Target: Target{Rollout: Rollout{Workloads: []Workload{{Kind: "Deployment", Name: "app"}, {Kind: "Deployment", Name: "app"}}}}},
+			fields: []string{"spec.target.rollout.workloads[1]"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ps := &ParameterStore{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Spec: tc.spec}
+			_, err := (&parameterStoreValidator{reader: testReader(t)}).ValidateCreate(context.TODO(), ps)
+			if len(tc.fields) == 0 {
+				assert.Nil(t, err)
+				return
+			}
+			assert.True(t, apierrors.IsInvalid(err))
+			var fields []string
+			for _, cause := range err.(*apierrors.StatusError).ErrStatus.Details.Causes {
+				fields = append(fields, cause.Field)
+			}
+			assert.Equal(t, tc.fields, fields)
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team", Annotations: map[string]string{
+		v1alpha1.DefaultStoreAnnotation:           "team-store",
+		v1alpha1.DefaultLabelAnnotation:           "prod",
+		v1alpha1.DefaultKeyMappingAnnotation:      v1alpha1.KeyMappingLastSegment,
+		v1alpha1.DefaultRefreshIntervalAnnotation: "5m",
+	}}}
+	defaulter := &parameterStoreDefaulter{reader: fake.NewClientBuilder().WithObjects(ns).Build()}
+
+	ps := &ParameterStore{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "team"},
+		Spec:       ParameterStoreSpec{StoreRef: StoreRef{Label: "dev"}},
+	}
+	assert.Nil(t, defaulter.Default(context.TODO(), ps))
+	assert.Equal(t, StoreRef{Name: "team-store", Label: "dev"}, ps.Spec.StoreRef)
+	assert.Equal(t, v1alpha1.KeyMappingLastSegment, ps.Spec.Target.KeyMapping)
+	assert.Equal(t, &metav1.Duration{Duration: 5 * time.Minute}, ps.Spec.RefreshInterval)
+}
+
+func TestValidateAccess(t *testing.T) {
+	validator := &parameterStoreValidator{reader: testReader(t,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments", Labels: map[string]string{"team": "payments"}}},
+		&v1alpha1.AppConfigAccessPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "payments"},
+			Spec: v1alpha1.AppConfigAccessPolicySpec{
+				NamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+				Rules:             []v1alpha1.AccessRule{{KeyPrefixes: []string{"/prod/payments/"}}},
+			},
+		})}
+
+	ps := &ParameterStore{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "payments"},
+		Spec:       ParameterStoreSpec{Sources: []Source{{Path: "/prod/payments/"}, {Key: "/prod/payments/db"}}},
+	}
+	_, err := validator.ValidateCreate(context.TODO(), ps)
+	assert.Nil(t, err)
+
+	ps.Spec.Sources = append(ps.Spec.Sources, Source{Key: "/prod/orders/url"})
+	_, err = validator.ValidateCreate(context.TODO(), ps)
+	assert.True(t, apierrors.IsForbidden(err))
+	assert.ErrorContains(t, err, "/prod/orders/url")
+}
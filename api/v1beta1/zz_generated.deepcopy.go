@@ -1,5 +1,4 @@
 //go:build !ignore_autogenerated
-// +build !ignore_autogenerated
 
 /*
 Copyright 2022.
@@ -19,16 +18,73 @@ limitations under the License.
 
 // Code generated by controller-gen. DO NOT EDIT.
 
-package v1alpha1
+package v1beta1
 
 import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChangeSource) DeepCopyInto(out *ChangeSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChangeSource.
+func (in *ChangeSource) DeepCopy() *ChangeSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ChangeSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DryRunStatus) DeepCopyInto(out *DryRunStatus) {
+	*out = *in
+	if in.Added != nil {
+		in, out := &in.Added, &out.Added
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Removed != nil {
+		in, out := &in.Removed, &out.Removed
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Changed != nil {
+		in, out := &in.Changed, &out.Changed
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]KeyStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DryRunStatus.
+func (in *DryRunStatus) DeepCopy() *DryRunStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DryRunStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KeyStatus) DeepCopyInto(out *KeyStatus) {
 	*out = *in
+	if in.LastModified != nil {
+		in, out := &in.LastModified, &out.LastModified
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyStatus.
@@ -100,25 +156,28 @@ func (in *ParameterStoreList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ParameterStoreRef) DeepCopyInto(out *ParameterStoreRef) {
-	*out = *in
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ParameterStoreRef.
-func (in *ParameterStoreRef) DeepCopy() *ParameterStoreRef {
-	if in == nil {
-		return nil
-	}
-	out := new(ParameterStoreRef)
-	in.DeepCopyInto(out)
-	return out
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ParameterStoreSpec) DeepCopyInto(out *ParameterStoreSpec) {
 	*out = *in
-	in.ValueFrom.DeepCopyInto(&out.ValueFrom)
+	out.StoreRef = in.StoreRef
+	if in.Sources != nil {
+		in, out := &in.Sources, &out.Sources
+		*out = make([]Source, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Target.DeepCopyInto(&out.Target)
+	if in.MaxStaleness != nil {
+		in, out := &in.MaxStaleness, &out.MaxStaleness
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.RefreshInterval != nil {
+		in, out := &in.RefreshInterval, &out.RefreshInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ParameterStoreSpec.
@@ -134,14 +193,14 @@ func (in *ParameterStoreSpec) DeepCopy() *ParameterStoreSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ParameterStoreStatus) DeepCopyInto(out *ParameterStoreStatus) {
 	*out = *in
-	if in.SecretStatus != nil {
-		in, out := &in.SecretStatus, &out.SecretStatus
+	if in.Secret != nil {
+		in, out := &in.Secret, &out.Secret
 		*out = new(SecretStatus)
 		**out = **in
 	}
-	if in.SSMStatus != nil {
-		in, out := &in.SSMStatus, &out.SSMStatus
-		*out = new(SSMStatus)
+	if in.Source != nil {
+		in, out := &in.Source, &out.Source
+		*out = new(SourceStatus)
 		(*in).DeepCopyInto(*out)
 	}
 	if in.Conditions != nil {
@@ -151,6 +210,20 @@ func (in *ParameterStoreStatus) DeepCopyInto(out *ParameterStoreStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.DryRun != nil {
+		in, out := &in.DryRun, &out.DryRun
+		*out = new(DryRunStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastChange != nil {
+		in, out := &in.LastChange, &out.LastChange
+		*out = new(SecretChangeStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ParameterStoreStatus.
@@ -164,36 +237,57 @@ func (in *ParameterStoreStatus) DeepCopy() *ParameterStoreStatus {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ParametersStoreRef) DeepCopyInto(out *ParametersStoreRef) {
+func (in *Rollout) DeepCopyInto(out *Rollout) {
 	*out = *in
+	if in.Workloads != nil {
+		in, out := &in.Workloads, &out.Workloads
+		*out = make([]Workload, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ParametersStoreRef.
-func (in *ParametersStoreRef) DeepCopy() *ParametersStoreRef {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Rollout.
+func (in *Rollout) DeepCopy() *Rollout {
 	if in == nil {
 		return nil
 	}
-	out := new(ParametersStoreRef)
+	out := new(Rollout)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SSMStatus) DeepCopyInto(out *SSMStatus) {
+func (in *SecretChangeStatus) DeepCopyInto(out *SecretChangeStatus) {
 	*out = *in
-	if in.Key != nil {
-		in, out := &in.Key, &out.Key
-		*out = make([]KeyStatus, len(*in))
+	if in.Added != nil {
+		in, out := &in.Added, &out.Added
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Removed != nil {
+		in, out := &in.Removed, &out.Removed
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Changed != nil {
+		in, out := &in.Changed, &out.Changed
+		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Sources != nil {
+		in, out := &in.Sources, &out.Sources
+		*out = make([]ChangeSource, len(*in))
+		copy(*out, *in)
+	}
+	in.Time.DeepCopyInto(&out.Time)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSMStatus.
-func (in *SSMStatus) DeepCopy() *SSMStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretChangeStatus.
+func (in *SecretChangeStatus) DeepCopy() *SecretChangeStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(SSMStatus)
+	out := new(SecretChangeStatus)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -214,26 +308,89 @@ func (in *SecretStatus) DeepCopy() *SecretStatus {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ValueFrom) DeepCopyInto(out *ValueFrom) {
+func (in *Source) DeepCopyInto(out *Source) {
 	*out = *in
-	if in.ParameterStoreRef != nil {
-		in, out := &in.ParameterStoreRef, &out.ParameterStoreRef
-		*out = new(ParameterStoreRef)
+	if in.Default != nil {
+		in, out := &in.Default, &out.Default
+		*out = new(string)
 		**out = **in
 	}
-	if in.ParametersStoreRef != nil {
-		in, out := &in.ParametersStoreRef, &out.ParametersStoreRef
-		*out = make([]ParametersStoreRef, len(*in))
-		copy(*out, *in)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Source.
+func (in *Source) DeepCopy() *Source {
+	if in == nil {
+		return nil
+	}
+	out := new(Source)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SourceStatus) DeepCopyInto(out *SourceStatus) {
+	*out = *in
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]KeyStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SourceStatus.
+func (in *SourceStatus) DeepCopy() *SourceStatus {
+	if in == nil {
+		return nil
 	}
+	out := new(SourceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoreRef) DeepCopyInto(out *StoreRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoreRef.
+func (in *StoreRef) DeepCopy() *StoreRef {
+	if in == nil {
+		return nil
+	}
+	out := new(StoreRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Target) DeepCopyInto(out *Target) {
+	*out = *in
+	in.Rollout.DeepCopyInto(&out.Rollout)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Target.
+func (in *Target) DeepCopy() *Target {
+	if in == nil {
+		return nil
+	}
+	out := new(Target)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Workload) DeepCopyInto(out *Workload) {
+	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValueFrom.
-func (in *ValueFrom) DeepCopy() *ValueFrom {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Workload.
+func (in *Workload) DeepCopy() *Workload {
 	if in == nil {
 		return nil
 	}
-	out := new(ValueFrom)
+	out := new(Workload)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/api/v1beta1"
+	"github.com/fr123k/az-app-config-operator/pkg/azure"
+)
+
+// generate lists the keys under a prefix and prints a ParameterStore, or a
+// kustomize patch of one, reading them. With -diff it compares the keys with
+// an existing manifest instead.
+func generate(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	prefix := fs.String("prefix", "", "The prefix of the keys, e.g. /stg/foo-app/.")
+	label := fs.String("label", "", "The label of the keys, keys without label are read if empty.")
+	storeName := fs.String("app-config-name", os.Getenv("APP_CONFIG_NAME"), "The name of the App Configuration store to read the keys from.")
+	store := fs.String("store", "", "The spec.store of the ParameterStore, the keys are read from it instead of -app-config-name.")
+	name := fs.String("name", "", "The name of the ParameterStore and its Secret.")
+	namespace := fs.String("namespace", "", "The namespace of the ParameterStore.")
+	keyMapping := fs.String("key-mapping", "", "The key mapping of the ParameterStore, UpperSnakeCase if empty.")
+	apiVersion := fs.String("api-version", v1alpha1.GroupVersion.String(), "The apiVersion of the ParameterStore.")
+	output := fs.String("o", "parameterstore", "The output, parameterstore or patch for a kustomize patch of the keys.")
+	diff := fs.String("diff", "", "The file with the ParameterStore to compare the keys with, - reads it from stdin.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *prefix == "" {
+		return errors.New("-prefix is required")
+	}
+	if *name == "" {
+		return errors.New("-name is required")
+	}
+	if *output != "parameterstore" && *output != "patch" {
+		return fmt.Errorf("unsupported output %s", *output)
+	}
+	if *apiVersion != v1alpha1.GroupVersion.String() && *apiVersion != v1beta1.GroupVersion.String() {
+		return fmt.Errorf("unsupported apiVersion %s", *apiVersion)
+	}
+	switch *keyMapping {
+	case "", v1alpha1.KeyMappingUpperSnakeCase, v1alpha1.KeyMappingLastSegment, v1alpha1.KeyMappingFullKey:
+	default:
+		return fmt.Errorf("unsupported key mapping %s", *keyMapping)
+	}
+
+	ps := &v1alpha1.ParameterStore{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "ParameterStore"},
+		ObjectMeta: metav1.ObjectMeta{Name: *name, Namespace: *namespace},
+		Spec:       v1alpha1.ParameterStoreSpec{Store: *store, Label: *label, KeyMapping: *keyMapping},
+	}
+	var existing *v1alpha1.ParameterStore
+	if *diff != "" {
+		var err error
+		if existing, err = findParameterStore(*diff, *name, *namespace); err != nil {
+			return err
+		}
+		// The keys are compared with the store, label and key mapping of the manifest unless set.
+		if err := ps.Spec.Default(map[string]string{
+			v1alpha1.DefaultStoreAnnotation:      existing.Spec.Store,
+			v1alpha1.DefaultLabelAnnotation:      existing.Spec.Label,
+			v1alpha1.DefaultKeyMappingAnnotation: orDefault(existing.Spec.KeyMapping, v1alpha1.KeyMappingUpperSnakeCase),
+		}); err != nil {
+			return err
+		}
+	}
+
+	appConfig, err := newClients(*storeName).get(ps)
+	if err != nil {
+		return err
+	}
+	params, err := listKeys(appConfig, *prefix, ps.Spec.Label)
+	if err != nil {
+		return err
+	}
+	refs, collisions := proposeRefs(params, ps.Spec.KeyMapping)
+
+	if existing != nil {
+		printDiff(stdout, *prefix, existing, ps.Spec.KeyMapping, refs)
+		for _, c := range collisions {
+			fmt.Fprintf(stdout, "Collision: %s\n", c)
+		}
+		return nil
+	}
+
+	ps.Spec.ValueFrom.ParametersStoreRef = refs
+	if len(params) == 0 {
+		fmt.Fprintf(stdout, "# No keys found under %s\n", *prefix)
+	}
+	for _, c := range collisions {
+		fmt.Fprintf(stdout, "# Collision: %s\n", c)
+	}
+	if *output == "patch" {
+		// The patch replaces the keys of the ParameterStore and keeps the rest of its spec.
+		ps.Spec = v1alpha1.ParameterStoreSpec{ValueFrom: ps.Spec.ValueFrom}
+	}
+	return printParameterStore(stdout, ps, *apiVersion)
+}
+
+// listKeys lists the settings under the prefix with the label, the operator
+// reads the keys of a ParameterStore only with its label.
+func listKeys(appConfig *azure.AppConfigClient, prefix, label string) ([]azure.Parameter, error) {
+	params, err := appConfig.ListParameters(prefix + "*")
+	if err != nil {
+		return nil, err
+	}
+	var result []azure.Parameter
+	for _, p := range params {
+		if p.Label == label {
+			result = append(result, p)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result, nil
+}
+
+// proposeRefs proposes a ParametersStoreRef for every setting. The names of
+// settings mapping to the same Secret key are derived from their full key,
+// collisions that remain are returned with the names of the refs.
+func proposeRefs(params []azure.Parameter, keyMapping string) ([]v1alpha1.ParametersStoreRef, []string) {
+	byName := make(map[string][]string, len(params))
+	for _, p := range params {
+		byName[p.Name] = append(byName[p.Name], p.Key)
+	}
+
+	refs := make([]v1alpha1.ParametersStoreRef, len(params))
+	proposed := make(map[string][]string, len(params))
+	var collisions []string
+	for i, p := range params {
+		refs[i].Key = p.Key
+		name := p.Name
+		if keys := byName[p.Name]; len(keys) > 1 {
+			name = fullKeyName(keyMapping, p.Key)
+			refs[i].Name = name
+			if p.Key == keys[0] {
+				collisions = append(collisions, fmt.Sprintf("%s of %s, named by their full key", p.Name, strings.Join(keys, ", ")))
+			}
+		}
+		proposed[name] = append(proposed[name], p.Key)
+	}
+	for name, keys := range proposed {
+		if len(keys) > 1 {
+			collisions = append(collisions, fmt.Sprintf("%s of %s, name them explicitly", name, strings.Join(keys, ", ")))
+		}
+	}
+	sort.Strings(collisions)
+	return refs, collisions
+}
+
+// fullKeyName names the setting by its full key in the style of the key mapping.
+func fullKeyName(keyMapping, key string) string {
+	name := v1alpha1.MapKey(v1alpha1.KeyMappingFullKey, key)
+	if keyMapping == "" || keyMapping == v1alpha1.KeyMappingUpperSnakeCase {
+		name = strings.ReplaceAll(strings.ToUpper(name), "-", "_")
+	}
+	return name
+}
+
+// printParameterStore prints the ParameterStore as manifest in the apiVersion.
+func printParameterStore(w io.Writer, ps *v1alpha1.ParameterStore, apiVersion string) error {
+	var obj interface{} = ps
+	if apiVersion == v1beta1.GroupVersion.String() {
+		spoke := &v1beta1.ParameterStore{}
+		if err := spoke.ConvertFrom(ps); err != nil {
+			return err
+		}
+		spoke.TypeMeta = metav1.TypeMeta{APIVersion: apiVersion, Kind: "ParameterStore"}
+		obj = spoke
+	}
+	out, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	// The status and the creation timestamp aren't part of a manifest.
+	var m map[string]interface{}
+	if err := yaml.Unmarshal(out, &m); err != nil {
+		return err
+	}
+	delete(m, "status")
+	if meta, ok := m["metadata"].(map[string]interface{}); ok {
+		delete(meta, "creationTimestamp")
+	}
+	if spec, ok := m["spec"].(map[string]interface{}); ok {
+		if valueFrom, ok := spec["valueFrom"].(map[string]interface{}); ok && valueFrom["parameterStoreRef"] == nil {
+			delete(valueFrom, "parameterStoreRef")
+		}
+	}
+	out, err = yaml.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// findParameterStore returns the ParameterStore with the name of the manifest,
+// in the namespace if set.
+func findParameterStore(file, name, namespace string) (*v1alpha1.ParameterStore, error) {
+	stores, err := readParameterStores(file, os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range stores {
+		if s.Name == name && (namespace == "" || s.Namespace == namespace) {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("no ParameterStore %s in %s", name, file)
+}
+
+// printDiff compares the proposed refs with the keys of the ParameterStore
+// under the prefix and prints the keys to add, to remove and to rename.
+func printDiff(w io.Writer, prefix string, existing *v1alpha1.ParameterStore, keyMapping string, refs []v1alpha1.ParametersStoreRef) {
+	current := make(map[string]string, len(existing.Spec.ValueFrom.ParametersStoreRef))
+	for _, ref := range existing.Spec.ValueFrom.ParametersStoreRef {
+		// Keys outside of the prefix aren't listed and can't be compared.
+		if strings.HasPrefix(ref.Key, prefix) {
+			current[ref.Key] = ref.SecretKey(existing.Spec.KeyMapping)
+		}
+	}
+	found := make(map[string]bool, len(refs))
+	var lines []string
+	for _, ref := range refs {
+		found[ref.Key] = true
+		name := ref.SecretKey(keyMapping)
+		got, ok := current[ref.Key]
+		switch {
+		case !ok:
+			lines = append(lines, fmt.Sprintf("+ %s as %s", ref.Key, name))
+		case got != name:
+			lines = append(lines, fmt.Sprintf("~ %s as %s instead of %s", ref.Key, name, got))
+		}
+	}
+	for key, name := range current {
+		if !found[key] {
+			lines = append(lines, fmt.Sprintf("- %s as %s", key, name))
+		}
+	}
+	// Sorted by the key, not by the kind of change.
+	sort.Slice(lines, func(i, j int) bool { return lines[i][2:] < lines[j][2:] })
+
+	if len(lines) == 0 {
+		fmt.Fprintf(w, "ParameterStore %s/%s is up to date\n", existing.Namespace, existing.Name)
+		return
+	}
+	fmt.Fprintf(w, "ParameterStore %s/%s\n", existing.Namespace, existing.Name)
+	for _, l := range lines {
+		fmt.Fprintf(w, "  %s\n", l)
+	}
+}
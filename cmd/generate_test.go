@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fr123k/az-app-config-operator/internal/testutil"
+)
+
+var generateSettings = map[string]string{
+	"/app/db-password":    "secret",
+	"/app/db/db-password": "other",
+	"/app/user":           "dbuser",
+	"/other/not-listed":   "value",
+}
+
+func TestGenerate(t *testing.T) {
+	testutil.AppConfigServer(t, generateSettings)
+
+	var out bytes.Buffer
+	assert.Nil(t, generate([]string{"-prefix", "/app/", "-name", "app", "-namespace", "team"}, &out))
+	assert.Equal(t, `# Collision: DB_PASSWORD of /app/db-password, /app/db/db-password, named by their full key
+apiVersion: ssm.aws/v1alpha1
+kind: ParameterStore
+metadata:
+  name: app
+  namespace: team
+spec:
+  valueFrom:
+    parametersStoreRef:
+    - key: /app/db-password
+      name: APP_DB_PASSWORD
+    - key: /app/db/db-password
+      name: APP_DB_DB_PASSWORD
+    - key: /app/user
+`, out.String())
+
+	out.Reset()
+	assert.Nil(t, generate([]string{"-prefix", "/app/", "-name", "app", "-key-mapping", "LastSegment", "-api-version", "ssm.aws/v1beta1", "-o", "patch"}, &out))
+	assert.Contains(t, out.String(), "apiVersion: ssm.aws/v1beta1\n")
+	assert.Contains(t, out.String(), "  - key: /app/db/db-password\n    secretKey: app_db_db-password\n")
+	assert.Contains(t, out.String(), "  - key: /app/user\n")
+	assert.NotContains(t, out.String(), "keyMapping")
+}
+
+func TestGenerateDiff(t *testing.T) {
+	testutil.AppConfigServer(t, generateSettings)
+	file := writeManifest(t, `
+apiVersion: ssm.aws/v1alpha1
+kind: ParameterStore
+metadata:
+  name: app
+  namespace: team
+spec:
+  keyMapping: LastSegment
+  valueFrom:
+    parametersStoreRef:
+    - key: /app/db-password
+      name: DB_PASSWORD
+    - key: /app/removed
+    - key: /other/not-listed
+`)
+
+	var out bytes.Buffer
+	assert.Nil(t, generate([]string{"-prefix", "/app/", "-name", "app", "-diff", file}, &out))
+	assert.Equal(t, `ParameterStore team/app
+  ~ /app/db-password as app_db-password instead of DB_PASSWORD
+  + /app/db/db-password as app_db_db-password
+  - /app/removed as removed
+  + /app/user as user
+Collision: db-password of /app/db-password, /app/db/db-password, named by their full key
+`, out.String())
+
+	assert.EqualError(t, generate([]string{"-prefix", "/app/", "-name", "missing", "-diff", file}, &out),
+		"no ParameterStore missing in "+file)
+}
+
+func TestGenerateFlags(t *testing.T) {
+	assert.EqualError(t, generate([]string{"-name", "app"}, &bytes.Buffer{}), "-prefix is required")
+	assert.EqualError(t, generate([]string{"-prefix", "/app/"}, &bytes.Buffer{}), "-name is required")
+	assert.EqualError(t, generate([]string{"-prefix", "/app/", "-name", "app", "-key-mapping", "Camel"}, &bytes.Buffer{}), "unsupported key mapping Camel")
+}
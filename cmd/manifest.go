@@ -0,0 +1,102 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/api/v1beta1"
+)
+
+// readParameterStores reads the ParameterStores of the YAML documents in the
+// file, - reads them from stdin. Other kinds are skipped and v1beta1 is
+// converted to v1alpha1, the version the operator syncs.
+func readParameterStores(file string, stdin io.Reader) ([]*v1alpha1.ParameterStore, error) {
+	r := stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var stores []*v1alpha1.ParameterStore
+	reader := yamlutil.NewYAMLReader(bufio.NewReader(r))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return stores, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		ps, err := decodeParameterStore(doc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", file, err)
+		}
+		if ps != nil {
+			stores = append(stores, ps)
+		}
+	}
+}
+
+// decodeParameterStore decodes the document, it returns nil for other kinds.
+func decodeParameterStore(doc []byte) (*v1alpha1.ParameterStore, error) {
+	var meta metav1.TypeMeta
+	if err := yaml.Unmarshal(doc, &meta); err != nil {
+		return nil, err
+	}
+	if meta.Kind != "ParameterStore" {
+		return nil, nil
+	}
+
+	ps := &v1alpha1.ParameterStore{}
+	switch meta.APIVersion {
+	case v1alpha1.GroupVersion.String():
+		if err := yaml.UnmarshalStrict(doc, ps); err != nil {
+			return nil, err
+		}
+	case v1beta1.GroupVersion.String():
+		spoke := &v1beta1.ParameterStore{}
+		if err := yaml.UnmarshalStrict(doc, spoke); err != nil {
+			return nil, err
+		}
+		if err := spoke.ConvertTo(ps); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported apiVersion %s of ParameterStore", meta.APIVersion)
+	}
+	if ps.Namespace == "" {
+		ps.Namespace = "default"
+	}
+	return ps, nil
+}
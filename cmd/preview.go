@@ -0,0 +1,244 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/controllers"
+	"github.com/fr123k/az-app-config-operator/pkg/azure"
+)
+
+// preview resolves the ParameterStores of a manifest like the operator and
+// prints their Secrets, it fails if any of them couldn't be synced.
+func preview(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("preview", flag.ContinueOnError)
+	file := fs.String("f", "", "The file with the ParameterStore manifests, - reads them from stdin.")
+	storeName := fs.String("app-config-name", os.Getenv("APP_CONFIG_NAME"), "The name of the App Configuration store of ParameterStores without spec.store.")
+	showValues := fs.Bool("show-values", false, "Print the values of the Secrets instead of masking them.")
+	output := fs.String("o", "text", "The output format, text or yaml.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("-f is required")
+	}
+	if *output != "text" && *output != "yaml" {
+		return fmt.Errorf("unsupported output format %s", *output)
+	}
+
+	stores, err := readParameterStores(*file, os.Stdin)
+	if err != nil {
+		return err
+	}
+	clients := newClients(*storeName)
+	failed := 0
+	for i, ps := range stores {
+		if i > 0 && *output == "yaml" {
+			fmt.Fprintln(stdout, "---")
+		} else if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+
+		var res *controllers.Resolution
+		appConfig, err := clients.get(ps)
+		if err == nil {
+			res, err = controllers.Resolve(appConfig, ps, &corev1.Secret{})
+		}
+		// The operator doesn't write the Secret if a key fails with the failure policy Fail.
+		written := res != nil && (err == nil || (ps.Spec.FailurePolicy != "" && ps.Spec.FailurePolicy != v1alpha1.FailurePolicyFail))
+		if !written {
+			failed++
+		}
+
+		p := printer{w: stdout, showValues: *showValues}
+		if *output == "yaml" {
+			if err := p.yaml(ps, res, err, written); err != nil {
+				return err
+			}
+		} else {
+			p.text(ps, res, err, written)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d ParameterStores can't be synced", failed, len(stores))
+	}
+	return nil
+}
+
+// clients creates one client per App Configuration store.
+type clients struct {
+	defaultStore string
+	byStore      map[string]*azure.AppConfigClient
+}
+
+func newClients(defaultStore string) *clients {
+	return &clients{defaultStore: defaultStore, byStore: map[string]*azure.AppConfigClient{}}
+}
+
+// get returns the client reading the settings of the ParameterStore from its
+// store with its label and key mapping.
+func (c *clients) get(ps *v1alpha1.ParameterStore) (*azure.AppConfigClient, error) {
+	store := ps.Spec.Store
+	if store == "" {
+		store = c.defaultStore
+	}
+	if store == "" && os.Getenv("LOCAL_STACK_ENDPOINT") == "" {
+		return nil, errors.New("no App Configuration store, set -app-config-name or spec.store")
+	}
+	cli, ok := c.byStore[store]
+	if !ok {
+		var err error
+		if cli, err = azure.NewAppClient(&store); err != nil {
+			return nil, err
+		}
+		cli.SetBatching(5, 4)
+		c.byStore[store] = cli
+	}
+	return cli.WithLabel(ps.Spec.Label).WithKeyMapping(ps.Spec.KeyMapping), nil
+}
+
+// printer prints the resolved Secret of a ParameterStore.
+type printer struct {
+	w          io.Writer
+	showValues bool
+}
+
+func (p printer) value(v string) string {
+	if p.showValues {
+		return v
+	}
+	return fmt.Sprintf("<%d bytes>", len(v))
+}
+
+func (p printer) text(ps *v1alpha1.ParameterStore, res *controllers.Resolution, err error, written bool) {
+	keyMapping := ps.Spec.KeyMapping
+	if keyMapping == "" {
+		keyMapping = v1alpha1.KeyMappingUpperSnakeCase
+	}
+	fmt.Fprintf(p.w, "ParameterStore %s/%s\n", ps.Namespace, ps.Name)
+	fmt.Fprintf(p.w, "  Store:       %s\n", orDefault(ps.Spec.Store, "operator store"))
+	fmt.Fprintf(p.w, "  Label:       %s\n", orDefault(ps.Spec.Label, "none"))
+	fmt.Fprintf(p.w, "  Key mapping: %s\n", keyMapping)
+	if res == nil {
+		fmt.Fprintf(p.w, "\nError: %v\n", err)
+		return
+	}
+
+	if written {
+		fmt.Fprintf(p.w, "\nSecret %s/%s\n", ps.Namespace, ps.Name)
+	} else {
+		fmt.Fprintf(p.w, "\nSecret %s/%s isn't written, the failure policy is Fail\n", ps.Namespace, ps.Name)
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "  KEY\tVALUE\tSETTING\tLABEL\tSTATE")
+	for _, k := range res.Keys() {
+		value := "-"
+		if v, ok := res.Data[k.Name]; ok {
+			value = p.value(v)
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", k.Name, value, k.Key, orDefault(k.Label, "-"), k.State)
+	}
+	_ = tw.Flush()
+
+	if len(res.Collisions) > 0 {
+		fmt.Fprintln(p.w, "\nCollisions:")
+		for _, c := range res.Collisions {
+			fmt.Fprintf(p.w, "  %s\n", c)
+		}
+	}
+	if len(res.Failed) > 0 {
+		fmt.Fprintln(p.w, "\nErrors:")
+		for _, k := range res.Failed {
+			fmt.Fprintf(p.w, "  %s (%s): %s\n", k.Name, k.Key, k.Error)
+		}
+	}
+}
+
+// yaml prints the Secret as manifest. Masked values are only printed as
+// comments, so applying the manifest can't overwrite the real values.
+func (p printer) yaml(ps *v1alpha1.ParameterStore, res *controllers.Resolution, err error, written bool) error {
+	if res == nil {
+		p.comment("ParameterStore %s/%s: %v", ps.Namespace, ps.Name, err)
+		return nil
+	}
+	for _, c := range res.Collisions {
+		p.comment("Collision: %s", c)
+	}
+	for _, k := range res.Failed {
+		p.comment("%s %s (%s): %s", k.State, k.Name, k.Key, k.Error)
+	}
+	if !written {
+		p.comment("Secret %s/%s isn't written, the failure policy is Fail", ps.Namespace, ps.Name)
+		return nil
+	}
+
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        ps.Name,
+			Namespace:   ps.Namespace,
+			Labels:      map[string]string{"app": ps.Name},
+			Annotations: res.Annotations,
+		},
+	}
+	if len(secret.Annotations) == 0 {
+		secret.Annotations = nil
+	}
+	if p.showValues {
+		secret.StringData = res.Data
+	} else {
+		p.comment("Data, set -show-values to print the values:")
+		for _, k := range slices.Sorted(maps.Keys(res.Data)) {
+			p.comment("  %s: %s", k, p.value(res.Data[k]))
+		}
+	}
+	out, err := yaml.Marshal(secret)
+	if err != nil {
+		return fmt.Errorf("failed to marshal Secret %s/%s: %w", ps.Namespace, ps.Name, err)
+	}
+	_, err = p.w.Write(out)
+	return err
+}
+
+// comment prints the message as yaml comment, every line of multi-line errors
+// is commented so the output stays valid yaml.
+func (p printer) comment(format string, args ...any) {
+	for _, line := range strings.Split(strings.TrimRight(fmt.Sprintf(format, args...), "\n"), "\n") {
+		fmt.Fprintf(p.w, "# %s\n", line)
+	}
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/controllers"
+	"github.com/fr123k/az-app-config-operator/internal/testutil"
+)
+
+func writeManifest(t *testing.T, manifest string) string {
+	file := filepath.Join(t.TempDir(), "parameterstore.yaml")
+	assert.Nil(t, os.WriteFile(file, []byte(manifest), 0o600))
+	return file
+}
+
+func TestPreview(t *testing.T) {
+	testutil.AppConfigServer(t, map[string]string{
+		"/app/db-password":   "secret",
+		"/other/db-password": "other",
+		"/app/user":          "dbuser",
+	})
+	file := writeManifest(t, `
+apiVersion: ssm.aws/v1alpha1
+kind: ParameterStore
+metadata:
+  name: app
+  namespace: team
+spec:
+  failurePolicy: Partial
+  valueFrom:
+    parametersStoreRef:
+    - key: /app/db-password
+    - key: /other/db-password
+    - key: /app/user
+      name: DB_USER
+    - key: /app/missing
+      name: MISSING
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: skipped
+`)
+
+	var out bytes.Buffer
+	assert.Nil(t, preview([]string{"-f", file}, &out))
+	assert.Contains(t, out.String(), "Secret team/app\n")
+	assert.Regexp(t, `DB_USER\s+<6 bytes>\s+/app/user\s+-\s+Synced`, out.String())
+	assert.Regexp(t, `MISSING\s+-\s+/app/missing\s+-\s+Failed`, out.String())
+	assert.Contains(t, out.String(), "DB_PASSWORD from /app/db-password overwritten by /other/db-password")
+	assert.Contains(t, out.String(), "MISSING (/app/missing): ")
+	assert.NotContains(t, out.String(), "dbuser")
+
+	// Masked values are only printed as comments, applying the Secret can't
+	// overwrite the real values with them.
+	out.Reset()
+	assert.Nil(t, preview([]string{"-f", file, "-o", "yaml"}, &out))
+	assert.Contains(t, out.String(), "#   DB_USER: <6 bytes>\n")
+	assert.NotContains(t, out.String(), "stringData")
+	assert.NotContains(t, out.String(), "dbuser")
+
+	out.Reset()
+	assert.Nil(t, preview([]string{"-f", file, "-show-values", "-o", "yaml"}, &out))
+	assert.Contains(t, out.String(), "DB_USER: dbuser")
+	assert.Contains(t, out.String(), "# Collision: DB_PASSWORD")
+	assert.Contains(t, out.String(), "ssm.aws/MISSING_error")
+}
+
+func TestPreviewV1beta1FailurePolicyFail(t *testing.T) {
+	testutil.AppConfigServer(t, map[string]string{"/app/db-password": "secret"})
+	file := writeManifest(t, `
+apiVersion: ssm.aws/v1beta1
+kind: ParameterStore
+metadata:
+  name: app
+spec:
+  sources:
+  - key: /app/db-password
+  - key: /app/missing
+  target:
+    keyMapping: LastSegment
+`)
+
+	var out bytes.Buffer
+	err := preview([]string{"-f", file}, &out)
+	assert.EqualError(t, err, "1 of 1 ParameterStores can't be synced")
+	assert.Contains(t, out.String(), "Key mapping: LastSegment")
+	assert.Contains(t, out.String(), "Secret default/app isn't written, the failure policy is Fail")
+	assert.Contains(t, out.String(), "missing (/app/missing): ")
+}
+
+func TestPrintYAMLMultiLineError(t *testing.T) {
+	ps := &v1alpha1.ParameterStore{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team"}}
+	res := &controllers.Resolution{
+		Data:   map[string]string{"USER": "dbuser"},
+		Failed: []v1alpha1.KeyStatus{{Name: "MISSING", Key: "/app/missing", State: v1alpha1.KeyStateFailed, Error: "GET /kv/%2Fapp%2Fmissing\n----\nRESPONSE 404: 404 Not Found\n"}},
+	}
+
+	var out bytes.Buffer
+	assert.Nil(t, printer{w: &out}.yaml(ps, res, nil, true))
+	assert.Contains(t, out.String(), "# RESPONSE 404: 404 Not Found\n")
+	secret := &corev1.Secret{}
+	assert.Nil(t, yaml.Unmarshal(out.Bytes(), secret))
+	assert.Equal(t, "app", secret.Name)
+}
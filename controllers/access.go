@@ -0,0 +1,119 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ssmv1alpha1 "github.com/fr123k/az-app-config-operator/api/v1alpha1"
+)
+
+//+kubebuilder:rbac:groups=ssm.aws,resources=appconfigaccesspolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// deny records that the AppConfigAccessPolicies of the namespace don't allow
+// the keys of the cr. The Secret isn't written, it waits for a change of the
+// spec or of the policies. Keys synced before the policies denied them are
+// removed from the Secret, so the namespace doesn't keep their values.
+func (r *ParameterStoreReconciler) deny(ctx context.Context, original, instance *ssmv1alpha1.ParameterStore, policies *ssmv1alpha1.AccessPolicies, err error) (ctrl.Result, error) {
+	logf.FromContext(ctx).Error(err, "Keys denied by the AppConfigAccessPolicies")
+	apimeta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Status:             metav1.ConditionTrue,
+		Reason:             ssmv1alpha1.AccessDeniedReason,
+		Message:            err.Error(),
+		Type:               ssmv1alpha1.ConditionTypeForbidden,
+		ObservedGeneration: instance.GetGeneration(),
+	})
+	if removeErr := r.removeDeniedKeys(ctx, instance, policies); removeErr != nil {
+		err = removeErr
+	}
+	return r.fail(ctx, original, instance, ssmv1alpha1.ConditionTypeReady, err)
+}
+
+// removeDeniedKeys removes the keys of the last sync the policies don't allow
+// anymore from the Secret, like deleteStaleSecret only the keys applied by the
+// operator are removed.
+func (r *ParameterStoreReconciler) removeDeniedKeys(ctx context.Context, instance *ssmv1alpha1.ParameterStore, policies *ssmv1alpha1.AccessPolicies) error {
+	if instance.Status.SSMStatus == nil {
+		return nil
+	}
+	denied := policies.Denied(instance.Spec.Store, instance.Status.SSMStatus.Key)
+	if len(denied) == 0 {
+		return nil
+	}
+
+	current := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, current); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	owned := ownedKeys(current)
+	patch := client.MergeFrom(current.DeepCopy())
+	var removed []string
+	for _, k := range denied {
+		if _, ok := current.Data[k.Name]; ok && owned[k.Name] {
+			delete(current.Data, k.Name)
+			removed = append(removed, k.Name)
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+
+	logf.FromContext(ctx).Info("Removing denied keys from Secret", "Secret.Name", current.Name, "keys", removed)
+	// The content hash doesn't match the data anymore, without it the next
+	// sync doesn't restore the keys as drifted.
+	delete(current.Annotations, ContentHashAnnotation)
+	spanCtx, span := startSpan(ctx, "Secret.Patch", attribute.String("k8s.name", current.Name))
+	err := r.Patch(spanCtx, current, patch, client.FieldOwner(FieldManager))
+	endSpan(span, err)
+	return err
+}
+
+// parameterStoresForPolicy maps a changed AppConfigAccessPolicy to all
+// ParameterStores, its namespace selector may select any namespace.
+func (r *ParameterStoreReconciler) parameterStoresForPolicy(ctx context.Context, _ client.Object) []reconcile.Request {
+	return r.parameterStores(ctx)
+}
+
+// parameterStoresInNamespace maps a Namespace whose labels changed to its
+// ParameterStores, other policies may select it now.
+func (r *ParameterStoreReconciler) parameterStoresInNamespace(ctx context.Context, ns client.Object) []reconcile.Request {
+	return r.parameterStores(ctx, client.InNamespace(ns.GetName()))
+}
+
+func (r *ParameterStoreReconciler) parameterStores(ctx context.Context, opts ...client.ListOption) []reconcile.Request {
+	list := &ssmv1alpha1.ParameterStoreList{}
+	if err := r.List(ctx, list, opts...); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list the ParameterStores to check their access")
+		return nil
+	}
+	requests := make([]reconcile.Request, len(list.Items))
+	for i, ps := range list.Items {
+		requests[i] = reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&ps)}
+	}
+	return requests
+}
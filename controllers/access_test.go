@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/internal/testutil"
+)
+
+func TestReconcileAccessDenied(t *testing.T) {
+	testutil.AppConfigServer(t, map[string]string{"app/user": "dbuser", "other/password": "dbpassword"})
+	parameterStore := testParameterStore()
+	parameterStore.Namespace = "team"
+	parameterStore.Spec.ValueFrom.ParametersStoreRef[0].Key = "app/user"
+	parameterStore.Spec.ValueFrom.ParametersStoreRef[1].Key = "other/password"
+	policy := &v1alpha1.AppConfigAccessPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "team"},
+		Spec: v1alpha1.AppConfigAccessPolicySpec{
+			NamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "app"}},
+			Rules:             []v1alpha1.AccessRule{{KeyPrefixes: []string{"app/"}}},
+		},
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team", Labels: map[string]string{"team": "app"}}}
+	r, cl, req := newTestReconciler(t, parameterStore, policy, ns)
+
+	// A key outside of the allowed prefixes isn't read, it waits for a change
+	// of the spec or of the policies.
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.True(t, errors.Is(err, reconcile.TerminalError(nil)))
+	assert.True(t, apierrors.IsNotFound(cl.Get(context.TODO(), req.NamespacedName, &corev1.Secret{})))
+
+	got := &v1alpha1.ParameterStore{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	forbidden := apimeta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeForbidden)
+	assert.Equal(t, metav1.ConditionTrue, forbidden.Status)
+	assert.Equal(t, v1alpha1.AccessDeniedReason, forbidden.Reason)
+	assert.Equal(t, "the AppConfigAccessPolicies of namespace team don't allow to read other/password", forbidden.Message)
+	ready := apimeta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeReady)
+	assert.Equal(t, metav1.ConditionFalse, ready.Status)
+	assert.Equal(t, v1alpha1.AccessDeniedReason, ready.Reason)
+
+	// The content type of the read settings is checked before the Secret is written.
+	policy.Spec.Rules = []v1alpha1.AccessRule{{KeyPrefixes: []string{"app/", "other/"}, ContentTypes: []string{"application/json"}}}
+	assert.Nil(t, cl.Update(context.TODO(), policy))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.True(t, errors.Is(err, reconcile.TerminalError(nil)))
+	assert.True(t, apierrors.IsNotFound(cl.Get(context.TODO(), req.NamespacedName, &corev1.Secret{})))
+
+	// Once the policies allow the settings the Secret is synced.
+	policy.Spec.Rules[0].ContentTypes = nil
+	assert.Nil(t, cl.Update(context.TODO(), policy))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, &corev1.Secret{}))
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.Nil(t, apimeta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeForbidden))
+	assert.Equal(t, metav1.ConditionTrue, apimeta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeReady).Status)
+
+	// A key denied after it was synced is removed from the Secret, the allowed
+	// keys and the keys of other tools are kept.
+	secret := &corev1.Secret{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, secret))
+	secret.Data["FOREIGN"] = []byte("foreign")
+	assert.Nil(t, cl.Update(context.TODO(), secret, client.FieldOwner("helm")))
+	policy.Spec.Rules[0].KeyPrefixes = []string{"app/"}
+	assert.Nil(t, cl.Update(context.TODO(), policy))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.True(t, errors.Is(err, reconcile.TerminalError(nil)))
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, secret))
+	assert.Equal(t, map[string][]byte{"DB_USER": []byte("dbuser"), "FOREIGN": []byte("foreign")}, secret.Data)
+
+	// Once allowed again the key is synced without being reported as drift.
+	policy.Spec.Rules[0].KeyPrefixes = []string{"app/", "other/"}
+	assert.Nil(t, cl.Update(context.TODO(), policy))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, secret))
+	assert.Equal(t, []byte("dbpassword"), secret.Data["DB_PASSWORD"])
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.False(t, apimeta.IsStatusConditionTrue(got.Status.Conditions, v1alpha1.ConditionTypeDrifted))
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	ssmv1alpha1 "github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/pkg/azure"
+)
+
+// dataChanges are the keys of the Secret the desired data adds, removes or
+// changes. Only keys applied by the operator before are removed by a sync.
+type dataChanges struct {
+	added, removed, changed []string
+}
+
+// diffData compares the data of the current Secret with the desired data.
+func diffData(current *corev1.Secret, desired map[string][]byte) dataChanges {
+	var c dataChanges
+	for k, v := range desired {
+		got, ok := current.Data[k]
+		switch {
+		case !ok:
+			c.added = append(c.added, k)
+		case !bytes.Equal(got, v):
+			c.changed = append(c.changed, k)
+		}
+	}
+	for k := range ownedKeys(current) {
+		if _, ok := desired[k]; !ok {
+			if _, ok := current.Data[k]; ok {
+				c.removed = append(c.removed, k)
+			}
+		}
+	}
+	sort.Strings(c.added)
+	sort.Strings(c.removed)
+	sort.Strings(c.changed)
+	return c
+}
+
+// String summarizes the changes by the names of the keys.
+func (c dataChanges) String() string {
+	var parts []string
+	for _, p := range []struct {
+		verb string
+		keys []string
+	}{{"added", c.added}, {"removed", c.removed}, {"changed", c.changed}} {
+		if len(p.keys) > 0 {
+			parts = append(parts, fmt.Sprintf("%s %s", p.verb, strings.Join(p.keys, ", ")))
+		}
+	}
+	if len(parts) == 0 {
+		return "no changes"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// secretChange returns the status of the changes, with the settings of the
+// keys synced now and of the removed keys as synced before. The time of the
+// previous change is kept if the same change is recorded again.
+func secretChange(changes dataChanges, keys []ssmv1alpha1.KeyStatus, previous *ssmv1alpha1.ParameterStoreStatus) *ssmv1alpha1.SecretChangeStatus {
+	source := func(name string, keys []ssmv1alpha1.KeyStatus) ssmv1alpha1.ChangeSource {
+		for _, k := range keys {
+			if k.Name == name {
+				return ssmv1alpha1.ChangeSource{Name: name, Key: k.Key, Label: k.Label, ETag: k.ETag}
+			}
+		}
+		return ssmv1alpha1.ChangeSource{Name: name}
+	}
+
+	change := &ssmv1alpha1.SecretChangeStatus{
+		Added:   changes.added,
+		Removed: changes.removed,
+		Changed: changes.changed,
+	}
+	for _, name := range append(append([]string{}, changes.added...), changes.changed...) {
+		change.Sources = append(change.Sources, source(name, keys))
+	}
+	if previous.SSMStatus != nil {
+		for _, name := range changes.removed {
+			change.Sources = append(change.Sources, source(name, previous.SSMStatus.Key))
+		}
+	}
+	sort.Slice(change.Sources, func(i, j int) bool { return change.Sources[i].Name < change.Sources[j].Name })
+
+	if previous.LastChange != nil {
+		change.Time = previous.LastChange.Time
+	}
+	if !apiequality.Semantic.DeepEqual(previous.LastChange, change) {
+		change.Time = metav1.Now()
+	}
+	return change
+}
+
+// changeMessage describes the changed keys of the Secret together with their settings.
+func changeMessage(change *ssmv1alpha1.SecretChangeStatus) string {
+	sources := make(map[string]ssmv1alpha1.ChangeSource, len(change.Sources))
+	for _, s := range change.Sources {
+		sources[s.Name] = s
+	}
+	describe := func(names []string) []string {
+		described := make([]string, len(names))
+		for i, name := range names {
+			s, ok := sources[name]
+			switch {
+			case !ok || s.Key == "":
+				described[i] = name
+			case s.ETag == "":
+				described[i] = fmt.Sprintf("%s (%s)", name, s.Key)
+			default:
+				described[i] = fmt.Sprintf("%s (%s, ETag %s)", name, s.Key, s.ETag)
+			}
+		}
+		return described
+	}
+	return dataChanges{
+		added:   describe(change.Added),
+		removed: describe(change.Removed),
+		changed: describe(change.Changed),
+	}.String()
+}
+
+// ownedKeys returns the keys of the Secret applied by the operator, as recorded
+// in its managed fields.
+func ownedKeys(secret *corev1.Secret) map[string]bool {
+	keys := map[string]bool{}
+	for _, entry := range secret.ManagedFields {
+		if entry.Manager != FieldManager || entry.FieldsV1 == nil {
+			continue
+		}
+		fields := map[string]map[string]interface{}{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		for f := range fields["f:data"] {
+			if k, ok := strings.CutPrefix(f, "f:"); ok {
+				keys[k] = true
+			}
+		}
+	}
+	return keys
+}
+
+// dryRun records the changes a sync would make to the Secret in the status of
+// the cr, instead of applying them. Neither the Secret nor the workloads are
+// touched, and the status of the last sync is kept.
+func (r *ParameterStoreReconciler) dryRun(ctx context.Context, original, instance *ssmv1alpha1.ParameterStore, current *corev1.Secret, desired *corev1ac.SecretApplyConfiguration, keys []ssmv1alpha1.KeyStatus, err error) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	var result ctrl.Result
+	var requeueErr error
+	var dryRun *ssmv1alpha1.DryRunStatus
+	if desired == nil {
+		if ssmErr, ok := err.(*azure.SSMError); ok && len(ssmErr.ParameterErrors) > 0 {
+			dryRun = &ssmv1alpha1.DryRunStatus{Keys: keys}
+		} else {
+			dryRun = &ssmv1alpha1.DryRunStatus{Error: err.Error()}
+		}
+		log.Error(err, "Failed to fetch SSM parameters in dry-run mode")
+
+		var reason string
+		reason, result, requeueErr = classify(err)
+		r.failureEvent(instance, reason, err)
+		apimeta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+			Status:             metav1.ConditionFalse,
+			Reason:             reason,
+			Message:            err.Error(),
+			Type:               ssmv1alpha1.ConditionTypeDryRun,
+			ObservedGeneration: instance.GetGeneration(),
+		})
+	} else {
+		changes := diffData(current, desired.Data)
+		dryRun = &ssmv1alpha1.DryRunStatus{
+			Added:   changes.added,
+			Removed: changes.removed,
+			Changed: changes.changed,
+			Keys:    keys,
+		}
+		message := fmt.Sprintf("Dry-run of Secret %s: %s", *desired.Name, changes)
+		if err != nil {
+			message = fmt.Sprintf("%s, keys failed: %v", message, err)
+		}
+		log.Info("Dry-run of the Secret", "Secret.Name", *desired.Name, "Changes", changes.String())
+		apimeta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+			Status:             metav1.ConditionTrue,
+			Reason:             ssmv1alpha1.ReconciliationSucceededReason,
+			Message:            message,
+			Type:               ssmv1alpha1.ConditionTypeDryRun,
+			ObservedGeneration: instance.GetGeneration(),
+		})
+		if instance.Spec.RefreshInterval != nil {
+			result.RequeueAfter = instance.Spec.RefreshInterval.Duration
+		}
+	}
+
+	// The time only moves forward if the result changed, so the status of a
+	// repeated dry-run isn't written again.
+	if original.Status.DryRun != nil {
+		dryRun.Time = original.Status.DryRun.Time
+	}
+	if !apiequality.Semantic.DeepEqual(original.Status.DryRun, dryRun) {
+		dryRun.Time = metav1.Now()
+	}
+	instance.Status.DryRun = dryRun
+
+	if err := r.patchStatus(ctx, original, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	return result, requeueErr
+}
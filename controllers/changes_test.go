@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/internal/testutil"
+)
+
+func TestReconcileDryRun(t *testing.T) {
+	testutil.AppConfigServer(t, map[string]string{"user": "dbuser", "user2": "other", "password": "dbpassword", "host": "db"})
+	parameterStore := testParameterStore()
+	parameterStore.Spec.DryRun = true
+	r, cl, req := newTestReconciler(t, parameterStore)
+
+	// The Secret isn't created in dry-run mode.
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	secret := &corev1.Secret{}
+	assert.True(t, apierrors.IsNotFound(cl.Get(context.TODO(), req.NamespacedName, secret)))
+
+	got := &v1alpha1.ParameterStore{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.Equal(t, []string{"DB_PASSWORD", "DB_USER"}, got.Status.DryRun.Added)
+	assert.Empty(t, got.Status.DryRun.Removed)
+	assert.Empty(t, got.Status.DryRun.Changed)
+	dryRun := apimeta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeDryRun)
+	assert.Equal(t, metav1.ConditionTrue, dryRun.Status)
+	assert.Equal(t, "Dry-run of Secret database: added DB_PASSWORD, DB_USER", dryRun.Message)
+	assert.Len(t, got.Status.DryRun.Keys, 2)
+	assert.Nil(t, got.Status.SecretStatus)
+	assert.Nil(t, got.Status.SSMStatus)
+
+	// A repeated dry-run with the same result doesn't write the status again.
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	repeated := &v1alpha1.ParameterStore{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, repeated))
+	assert.Equal(t, got.ResourceVersion, repeated.ResourceVersion)
+	assert.Equal(t, got.Status.DryRun, repeated.Status.DryRun)
+
+	// Once the dry-run mode is switched off the Secret is synced.
+	got.Spec.DryRun = false
+	assert.Nil(t, cl.Update(context.TODO(), got))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, secret))
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.Nil(t, got.Status.DryRun)
+	assert.Nil(t, apimeta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeDryRun))
+
+	// The effect of a new key, a removed one and a changed value is only reported.
+	got.Spec.DryRun = true
+	got.Spec.ValueFrom.ParametersStoreRef = []v1alpha1.ParametersStoreRef{
+		{Name: "DB_USER", Key: "user2"},
+		{Name: "DB_HOST", Key: "host"},
+	}
+	assert.Nil(t, cl.Update(context.TODO(), got))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.Equal(t, []string{"DB_HOST"}, got.Status.DryRun.Added)
+	assert.Equal(t, []string{"DB_PASSWORD"}, got.Status.DryRun.Removed)
+	assert.Equal(t, []string{"DB_USER"}, got.Status.DryRun.Changed)
+	assert.Equal(t, "Dry-run of Secret database: added DB_HOST; removed DB_PASSWORD; changed DB_USER",
+		apimeta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeDryRun).Message)
+	settings := func(keys []v1alpha1.KeyStatus) []string {
+		var settings []string
+		for _, k := range keys {
+			settings = append(settings, k.Key)
+		}
+		return settings
+	}
+	assert.ElementsMatch(t, []string{"user2", "host"}, settings(got.Status.DryRun.Keys))
+	// The keys of the last sync are kept for the next one.
+	assert.ElementsMatch(t, []string{"user", "password"}, settings(got.Status.SSMStatus.Key))
+
+	unchanged := &corev1.Secret{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, unchanged))
+	assert.Equal(t, secret.Data, unchanged.Data)
+}
+
+func TestReconcileDryRunFailure(t *testing.T) {
+	testutil.AppConfigServer(t, map[string]string{})
+	parameterStore := testParameterStore()
+	parameterStore.Spec.DryRun = true
+	r, cl, req := newTestReconciler(t, parameterStore)
+
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+
+	got := &v1alpha1.ParameterStore{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.True(t, apimeta.IsStatusConditionFalse(got.Status.Conditions, v1alpha1.ConditionTypeDryRun))
+	assert.Equal(t, v1alpha1.KeyNotFoundReason, apimeta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeDryRun).Reason)
+	assert.Equal(t, v1alpha1.KeyStateFailed, got.Status.DryRun.Keys[0].State)
+	assert.Nil(t, got.Status.SSMStatus)
+	assert.Nil(t, apimeta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeReady))
+}
+
+func TestReconcileRecordsLastChange(t *testing.T) {
+	testutil.AppConfigServer(t, map[string]string{"user": "dbuser", "user2": "admin", "password": "dbpassword", "host": "db"})
+	r, cl, req := newTestReconciler(t, testParameterStore())
+	recorder := events.NewFakeRecorder(10)
+	r.Recorder = recorder
+
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, "Normal SecretCreated Secret database created with 2 keys", <-recorder.Events)
+
+	got := &v1alpha1.ParameterStore{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.Equal(t, []string{"DB_PASSWORD", "DB_USER"}, got.Status.LastChange.Added)
+	assert.Len(t, got.Status.LastChange.Sources, 2)
+
+	got.Spec.ValueFrom.ParametersStoreRef = []v1alpha1.ParametersStoreRef{
+		{Name: "DB_USER", Key: "user2"},
+		{Name: "DB_HOST", Key: "host"},
+	}
+	assert.Nil(t, cl.Update(context.TODO(), got))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+
+	etag := "4f6dd610dd5e4deebc7fbaef685fb903"
+	assert.Equal(t, "Normal SecretUpdated Secret database updated: added DB_HOST (host, ETag "+etag+
+		"); removed DB_PASSWORD (password, ETag "+etag+"); changed DB_USER (user2, ETag "+etag+")", <-recorder.Events)
+
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	change := got.Status.LastChange
+	assert.Equal(t, []string{"DB_HOST"}, change.Added)
+	assert.Equal(t, []string{"DB_PASSWORD"}, change.Removed)
+	assert.Equal(t, []string{"DB_USER"}, change.Changed)
+	assert.Equal(t, []v1alpha1.ChangeSource{
+		{Name: "DB_HOST", Key: "host", ETag: etag},
+		{Name: "DB_PASSWORD", Key: "password", ETag: etag},
+		{Name: "DB_USER", Key: "user2", ETag: etag},
+	}, change.Sources)
+
+	// An unchanged Secret keeps the last change.
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Empty(t, recorder.Events)
+	unchanged := &v1alpha1.ParameterStore{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, unchanged))
+	assert.Equal(t, change, unchanged.Status.LastChange)
+}
+
+func TestSecretChangeKeepsTime(t *testing.T) {
+	keys := []v1alpha1.KeyStatus{{Name: "DB_USER", Key: "user", ETag: "etag"}}
+	changes := dataChanges{changed: []string{"DB_USER"}}
+	yesterday := metav1.NewTime(time.Now().Add(-24 * time.Hour).Truncate(time.Second))
+
+	previous := &v1alpha1.ParameterStoreStatus{LastChange: secretChange(changes, keys, &v1alpha1.ParameterStoreStatus{})}
+	previous.LastChange.Time = yesterday
+	assert.Equal(t, yesterday, secretChange(changes, keys, previous).Time)
+
+	keys[0].ETag = "other"
+	assert.True(t, secretChange(changes, keys, previous).Time.After(yesterday.Time))
+}
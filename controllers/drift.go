@@ -0,0 +1,101 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	ssmv1alpha1 "github.com/fr123k/az-app-config-operator/api/v1alpha1"
+)
+
+// driftManagers returns the field managers, other than the operator, that
+// changed one of the keys of the Secret, with the time of their last change.
+func driftManagers(secret *corev1.Secret, keys map[string][]byte) []string {
+	var managers []string
+	for _, entry := range secret.ManagedFields {
+		if entry.Manager == FieldManager || entry.FieldsV1 == nil {
+			continue
+		}
+		fields := map[string]map[string]interface{}{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		for k := range keys {
+			if _, ok := fields["f:data"]["f:"+k]; ok {
+				manager := entry.Manager
+				if entry.Time != nil {
+					manager = fmt.Sprintf("%s at %s", manager, entry.Time.Format(time.RFC3339))
+				}
+				managers = append(managers, manager)
+				break
+			}
+		}
+	}
+	sort.Strings(managers)
+	return managers
+}
+
+// recordDrift sets the Drifted condition and raises an event about the
+// Secret that was changed or deleted outside of the operator.
+func (r *ParameterStoreReconciler) recordDrift(ctx context.Context, instance *ssmv1alpha1.ParameterStore, reason, message string) {
+	logf.FromContext(ctx).Info("Secret drifted from the applied content", "Reason", reason, "Message", message)
+
+	apimeta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		Type:               ssmv1alpha1.ConditionTypeDrifted,
+		ObservedGeneration: instance.GetGeneration(),
+	})
+	if r.Recorder != nil {
+		r.Recorder.Eventf(instance, nil, corev1.EventTypeWarning, reason, "Restore", "%s", message)
+	}
+}
+
+// driftMessage describes who modified the Secret, if known from the managed fields.
+func driftMessage(secret *corev1.Secret, keys map[string][]byte) string {
+	managers := driftManagers(secret, keys)
+	if len(managers) == 0 {
+		return fmt.Sprintf("Secret %s was modified outside of the operator and restored", secret.Name)
+	}
+	return fmt.Sprintf("Secret %s was modified by %s and restored", secret.Name, strings.Join(managers, ", "))
+}
+
+// resolveDrift marks a previously reported drift as resolved once the Secret matches again.
+func resolveDrift(instance *ssmv1alpha1.ParameterStore) {
+	drifted := apimeta.FindStatusCondition(instance.Status.Conditions, ssmv1alpha1.ConditionTypeDrifted)
+	if drifted == nil || drifted.Status != metav1.ConditionTrue {
+		return
+	}
+	apimeta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Status:             metav1.ConditionFalse,
+		Reason:             ssmv1alpha1.SecretRestoredReason,
+		Message:            drifted.Message,
+		Type:               ssmv1alpha1.ConditionTypeDrifted,
+		ObservedGeneration: instance.GetGeneration(),
+	})
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/internal/testutil"
+)
+
+func TestReconcileRestoresDriftedSecret(t *testing.T) {
+	testutil.AppConfigServer(t, map[string]string{"user": "dbuser"})
+	parameterStore := testParameterStore()
+	parameterStore.Spec.ValueFrom.ParametersStoreRef = parameterStore.Spec.ValueFrom.ParametersStoreRef[:1]
+	r, cl, req := newTestReconciler(t, parameterStore)
+	recorder := events.NewFakeRecorder(10)
+	r.Recorder = recorder
+
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, "Normal SecretCreated Secret database created with 1 keys", <-recorder.Events)
+
+	// Someone edits the Secret by hand.
+	secret := &corev1.Secret{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, secret))
+	secret.Data["DB_USER"] = []byte("hacked")
+	assert.Nil(t, cl.Update(context.TODO(), secret, client.FieldOwner("kubectl-edit")))
+
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, secret))
+	assert.Equal(t, "dbuser", string(secret.Data["DB_USER"]))
+
+	got := &v1alpha1.ParameterStore{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	drifted := apimeta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeDrifted)
+	assert.NotNil(t, drifted)
+	assert.Equal(t, metav1.ConditionTrue, drifted.Status)
+	assert.Equal(t, v1alpha1.SecretModifiedReason, drifted.Reason)
+	assert.Contains(t, drifted.Message, "kubectl-edit")
+	assert.Contains(t, <-recorder.Events, "Warning SecretModified")
+
+	// The next reconcile finds the restored Secret.
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.True(t, apimeta.IsStatusConditionFalse(got.Status.Conditions, v1alpha1.ConditionTypeDrifted))
+
+	// Someone deletes the Secret.
+	assert.Nil(t, cl.Delete(context.TODO(), secret))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, secret))
+	assert.Equal(t, "dbuser", string(secret.Data["DB_USER"]))
+	assert.Contains(t, <-recorder.Events, "Warning SecretDeleted")
+}
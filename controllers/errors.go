@@ -0,0 +1,83 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ssmv1alpha1 "github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/pkg/azure"
+)
+
+const (
+	// ThrottledRequeueDelay is the delay before a throttled request is retried
+	// if the store didn't send a Retry-After.
+	ThrottledRequeueDelay = 30 * time.Second
+	// BackoffRequeueDelay is the delay before errors are retried that need a
+	// change outside of the cluster, like a missing key or a missing role assignment.
+	BackoffRequeueDelay = 5 * time.Minute
+)
+
+// errorClasses maps the error classes to their condition reason. If an error
+// has several classes, e.g. one per missing parameter, the first one wins so
+// the request is retried as early as any of them needs it.
+var errorClasses = []struct {
+	class  error
+	reason string
+}{
+	{azure.ErrThrottled, ssmv1alpha1.ThrottledReason},
+	{azure.ErrTransient, ssmv1alpha1.TransientErrorReason},
+	{azure.ErrUnauthorized, ssmv1alpha1.UnauthorizedReason},
+	{azure.ErrForbidden, ssmv1alpha1.ForbiddenReason},
+	{azure.ErrNotFound, ssmv1alpha1.KeyNotFoundReason},
+	{azure.ErrInvalidRef, ssmv1alpha1.InvalidRefReason},
+}
+
+// classify returns the condition reason of the error and how the request is retried:
+// transient and unknown errors are returned to retry them with the rate limiter's
+// backoff, throttled requests and errors needing an external change are requeued
+// after a delay, and invalid references and keys denied by the access policies
+// wait for a change of the spec or the policies. A Retry-After sent by the store
+// with a throttled or transient error is respected.
+func classify(err error) (string, ctrl.Result, error) {
+	var denied *ssmv1alpha1.AccessDeniedError
+	if errors.As(err, &denied) {
+		return ssmv1alpha1.AccessDeniedReason, ctrl.Result{}, reconcile.TerminalError(err)
+	}
+	for _, c := range errorClasses {
+		if !errors.Is(err, c.class) {
+			continue
+		}
+		if after := azure.RetryAfter(err); after > 0 && (c.class == azure.ErrThrottled || c.class == azure.ErrTransient) {
+			return c.reason, ctrl.Result{RequeueAfter: after}, nil
+		}
+		switch c.class {
+		case azure.ErrThrottled:
+			return c.reason, ctrl.Result{RequeueAfter: ThrottledRequeueDelay}, nil
+		case azure.ErrUnauthorized, azure.ErrForbidden, azure.ErrNotFound:
+			return c.reason, ctrl.Result{RequeueAfter: BackoffRequeueDelay}, nil
+		case azure.ErrInvalidRef:
+			return c.reason, ctrl.Result{}, reconcile.TerminalError(err)
+		}
+		return c.reason, ctrl.Result{}, err
+	}
+	return ssmv1alpha1.ReconciliationFailedReason, ctrl.Result{}, err
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig"
+	"github.com/stretchr/testify/assert"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/pkg/azure"
+)
+
+func TestReconcileClassifiesErrors(t *testing.T) {
+	for _, tc := range []struct {
+		status   int
+		reason   string
+		result   ctrl.Result
+		retry    bool
+		terminal bool
+	}{
+		{http.StatusNotFound, v1alpha1.KeyNotFoundReason, ctrl.Result{RequeueAfter: BackoffRequeueDelay}, false, false},
+		{http.StatusForbidden, v1alpha1.ForbiddenReason, ctrl.Result{RequeueAfter: BackoffRequeueDelay}, false, false},
+		{http.StatusTooManyRequests, v1alpha1.ThrottledReason, ctrl.Result{RequeueAfter: ThrottledRequeueDelay}, false, false},
+		{http.StatusServiceUnavailable, v1alpha1.TransientErrorReason, ctrl.Result{}, true, false},
+		{http.StatusBadRequest, v1alpha1.InvalidRefReason, ctrl.Result{}, true, true},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("Sync-Token", "id=value;sn=0")
+			rw.WriteHeader(tc.status)
+			_, _ = rw.Write([]byte(`{"message": "failed"}`))
+		}))
+		t.Setenv("LOCAL_STACK_ENDPOINT", server.URL)
+		r, cl, req := newTestReconciler(t, testParameterStore())
+		// Avoid the retries of the azcore pipeline for transient errors.
+		r.AppConfig.Client = newAppConfigClientWithoutRetries(t, server.URL)
+
+		result, err := r.Reconcile(context.TODO(), req)
+		server.Close()
+
+		assert.Equal(t, tc.result, result, "status %d", tc.status)
+		assert.Equal(t, tc.retry, err != nil, "status %d", tc.status)
+		assert.Equal(t, tc.terminal, errors.Is(err, reconcile.TerminalError(nil)), "status %d", tc.status)
+
+		got := &v1alpha1.ParameterStore{}
+		assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+		ready := apimeta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeReady)
+		if assert.NotNil(t, ready) {
+			assert.Equal(t, tc.reason, ready.Reason, "status %d", tc.status)
+		}
+		missing := apimeta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeSSMParamMissing)
+		if assert.NotNil(t, missing) {
+			assert.Equal(t, tc.reason, missing.Reason, "status %d", tc.status)
+		}
+	}
+}
+
+func newAppConfigClientWithoutRetries(t *testing.T, endpoint string) *azappconfig.Client {
+	connStr := fmt.Sprintf("Endpoint=%s;Id=test;Secret=%s", endpoint, base64.StdEncoding.EncodeToString([]byte("test")))
+	client, err := azappconfig.NewClientFromConnectionString(connStr, &azappconfig.ClientOptions{
+		ClientOptions: policy.ClientOptions{Retry: policy.RetryOptions{MaxRetries: -1}},
+	})
+	assert.Nil(t, err)
+	return client
+}
+
+func TestClassifyRetryAfter(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": {"12"}},
+		Request:    httptest.NewRequest(http.MethodGet, "https://test.azconfig.io/kv/user", nil),
+	}
+	err := &azure.SSMError{Err: azure.Classify(azruntime.NewResponseError(resp))}
+
+	reason, result, requeueErr := classify(err)
+	assert.Equal(t, v1alpha1.ThrottledReason, reason)
+	assert.Equal(t, ctrl.Result{RequeueAfter: 12 * time.Second}, result)
+	assert.Nil(t, requeueErr)
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	ssmv1alpha1 "github.com/fr123k/az-app-config-operator/api/v1alpha1"
+)
+
+// EventDedupInterval is the time a warning event isn't raised again for the
+// same ParameterStore with the same reason and message.
+const EventDedupInterval = time.Hour
+
+// maxEventNoteLength is the length of the note of an event the API server accepts.
+const maxEventNoteLength = 1024
+
+type eventKey struct {
+	uid     types.UID
+	reason  string
+	message string
+}
+
+// eventDeduper remembers the warning events raised recently, so a failure
+// retried on every reconcile raises one event instead of one per retry.
+type eventDeduper struct {
+	mu   sync.Mutex
+	sent map[eventKey]time.Time
+}
+
+// first reports whether the event wasn't raised within the dedup interval.
+func (d *eventDeduper) first(key eventKey, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if t, ok := d.sent[key]; ok && now.Sub(t) < EventDedupInterval {
+		return false
+	}
+	if d.sent == nil {
+		d.sent = map[eventKey]time.Time{}
+	}
+	for k, t := range d.sent {
+		if now.Sub(t) >= EventDedupInterval {
+			delete(d.sent, k)
+		}
+	}
+	d.sent[key] = now
+	return true
+}
+
+// event raises an event on the cr. Normal events report a change of the
+// Secret and are always raised, repeated warnings are de-duplicated.
+func (r *ParameterStoreReconciler) event(instance *ssmv1alpha1.ParameterStore, eventtype, reason, action, message string) {
+	if r.Recorder == nil {
+		return
+	}
+	if eventtype == corev1.EventTypeWarning && !r.events.first(eventKey{uid: instance.UID, reason: reason, message: message}, time.Now()) {
+		return
+	}
+	if len(message) > maxEventNoteLength {
+		message = message[:maxEventNoteLength-3] + "..."
+	}
+	r.Recorder.Eventf(instance, nil, eventtype, reason, action, "%s", message)
+}
+
+// failureEvents maps the condition reasons of failed syncs to the reason of their event.
+var failureEvents = map[string]string{
+	ssmv1alpha1.KeyNotFoundReason:  ssmv1alpha1.KeyNotFoundReason,
+	ssmv1alpha1.UnauthorizedReason: ssmv1alpha1.AuthFailedReason,
+	ssmv1alpha1.ForbiddenReason:    ssmv1alpha1.AuthFailedReason,
+	ssmv1alpha1.ThrottledReason:    ssmv1alpha1.ThrottledReason,
+	ssmv1alpha1.AccessDeniedReason: ssmv1alpha1.AccessDeniedReason,
+}
+
+// failureEvent raises a warning event about a failed sync, if its reason is
+// worth alerting on. Transient errors are retried and only visible in the conditions.
+func (r *ParameterStoreReconciler) failureEvent(instance *ssmv1alpha1.ParameterStore, reason string, err error) {
+	if eventReason, ok := failureEvents[reason]; ok {
+		r.event(instance, corev1.EventTypeWarning, eventReason, "Sync", err.Error())
+	}
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+)
+
+func eventsReconciler(t *testing.T, handler http.HandlerFunc, refs ...v1alpha1.ParametersStoreRef) (*ParameterStoreReconciler, *events.FakeRecorder, ctrl.Request) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Sync-Token", "id=value;sn=0")
+		handler(rw, req)
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("LOCAL_STACK_ENDPOINT", server.URL)
+	parameterStore := testParameterStore()
+	parameterStore.Name = "events"
+	parameterStore.Spec.ValueFrom.ParametersStoreRef = refs
+	r, _, req := newTestReconciler(t, parameterStore)
+	r.AppConfig.Client = newAppConfigClientWithoutRetries(t, server.URL)
+	recorder := events.NewFakeRecorder(10)
+	r.Recorder = recorder
+	return r, recorder, req
+}
+
+func TestReconcileSecretEvents(t *testing.T) {
+	var value atomic.Value
+	value.Store("dbuser")
+	r, recorder, req := eventsReconciler(t, func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte(`{"key": "user", "value": "` + value.Load().(string) + `"}`))
+	}, v1alpha1.ParametersStoreRef{Name: "DB_USER", Key: "user"})
+
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, "Normal SecretCreated Secret events created with 1 keys", <-recorder.Events)
+
+	// An unchanged Secret raises no event.
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Empty(t, recorder.Events)
+
+	value.Store("admin")
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, "Normal SecretUpdated Secret events updated: changed DB_USER (user)", <-recorder.Events)
+}
+
+func TestReconcileFailureEventsAreDeduplicated(t *testing.T) {
+	var code atomic.Int32
+	code.Store(http.StatusNotFound)
+	r, recorder, req := eventsReconciler(t, func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(int(code.Load()))
+	}, v1alpha1.ParametersStoreRef{Name: "DB_USER", Key: "user"})
+
+	for i := 0; i < 3; i++ {
+		_, err := r.Reconcile(context.TODO(), req)
+		assert.Nil(t, err)
+	}
+	assert.Contains(t, <-recorder.Events, "Warning KeyNotFound")
+	assert.Empty(t, recorder.Events)
+
+	code.Store(http.StatusForbidden)
+	for i := 0; i < 3; i++ {
+		_, err := r.Reconcile(context.TODO(), req)
+		assert.Nil(t, err)
+	}
+	assert.Contains(t, <-recorder.Events, "Warning AuthFailed")
+	assert.Empty(t, recorder.Events)
+
+	code.Store(http.StatusTooManyRequests)
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Contains(t, <-recorder.Events, "Warning Throttled")
+}
+
+func TestReconcileCollisionDetected(t *testing.T) {
+	r, recorder, req := eventsReconciler(t, func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte(`{"key": "` + req.URL.Path[len("/kv/"):] + `", "value": "value"}`))
+	}, v1alpha1.ParametersStoreRef{Name: "DB_USER", Key: "app1/user"}, v1alpha1.ParametersStoreRef{Name: "DB_USER", Key: "app2/user"})
+
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Contains(t, <-recorder.Events, "Warning CollisionDetected Several keys map to the same Secret key: DB_USER from app")
+	assert.Equal(t, "Normal SecretCreated Secret events created with 1 keys", <-recorder.Events)
+
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Empty(t, recorder.Events)
+}
+
+func TestEventDeduper(t *testing.T) {
+	d := eventDeduper{}
+	key := eventKey{uid: "1234", reason: v1alpha1.KeyNotFoundReason, message: "Key not found"}
+	now := time.Now()
+
+	assert.True(t, d.first(key, now))
+	assert.False(t, d.first(key, now.Add(time.Minute)))
+	assert.True(t, d.first(eventKey{uid: "5678", reason: key.reason, message: key.message}, now))
+	assert.True(t, d.first(key, now.Add(EventDedupInterval)))
+}
+
+func TestReconcileEventMessageWithPercent(t *testing.T) {
+	r, recorder, req := eventsReconciler(t, func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+	}, v1alpha1.ParametersStoreRef{Name: "DB_USER", Key: "app/user"})
+
+	// The error of the key contains its URL-encoded name, which isn't a format.
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	event := <-recorder.Events
+	assert.Contains(t, event, "app%2Fuser")
+	assert.NotContains(t, event, "%!")
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"errors"
+
+	corev1 "k8s.io/api/core/v1"
+
+	ssmv1alpha1 "github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/pkg/azure"
+)
+
+// resolveFailures applies the optional keys, their defaults and the failure
+// policy to the keys of the ParametersStoreRef that couldn't be fetched. It
+// returns the values used instead of the failed keys, the status of all failed
+// keys and the errors of the keys that still fail the sync.
+func resolveFailures(cr *ssmv1alpha1.ParameterStore, ssmErr *azure.SSMError, current *corev1.Secret) (map[string]string, []ssmv1alpha1.KeyStatus, *azure.SSMError) {
+	values := make(map[string]string)
+	keys := make([]ssmv1alpha1.KeyStatus, 0, len(ssmErr.ParameterErrors))
+	var failed []azure.ParameterError
+
+	for _, pe := range ssmErr.ParameterErrors {
+		ref := findRef(cr.Spec.ValueFrom.ParametersStoreRef, pe)
+		name := pe.Name
+		if name == "" {
+			name = ssmv1alpha1.MapKey(cr.Spec.KeyMapping, pe.Key)
+		}
+		ks := ssmv1alpha1.KeyStatus{Name: name, Key: pe.Key, Error: pe.Err.Error()}
+		notFound := errors.Is(pe.Err, azure.ErrNotFound)
+
+		switch {
+		case notFound && ref.Default != nil:
+			values[name] = *ref.Default
+			ks.State = ssmv1alpha1.KeyStateDefaulted
+		case notFound && ref.Optional:
+			ks.State = ssmv1alpha1.KeyStateMissing
+		default:
+			ks.State = ssmv1alpha1.KeyStateFailed
+			if v, ok := current.Data[name]; ok && cr.Spec.FailurePolicy == ssmv1alpha1.FailurePolicyKeepLastKnown {
+				values[name] = string(v)
+				ks.State = ssmv1alpha1.KeyStateLastKnown
+			}
+			failed = append(failed, pe)
+		}
+		keys = append(keys, ks)
+	}
+
+	if len(failed) == 0 {
+		return values, keys, nil
+	}
+	return values, keys, &azure.SSMError{ParameterErrors: failed}
+}
+
+// findRef returns the ref the parameter error belongs to.
+func findRef(refs []ssmv1alpha1.ParametersStoreRef, pe azure.ParameterError) ssmv1alpha1.ParametersStoreRef {
+	for _, ref := range refs {
+		if ref.Name == pe.Name && ref.Key == pe.Key {
+			return ref
+		}
+	}
+	return ssmv1alpha1.ParametersStoreRef{Name: pe.Name, Key: pe.Key}
+}
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/internal/testutil"
+)
+
+func failurePolicyReconciler(t *testing.T, policy string, objs ...client.Object) (*ParameterStoreReconciler, client.Client) {
+	testutil.AppConfigServer(t, map[string]string{"user": "dbuser"})
+	parameterStore := testParameterStore()
+	parameterStore.Spec.FailurePolicy = policy
+	parameterStore.Spec.ValueFrom.ParametersStoreRef = append(parameterStore.Spec.ValueFrom.ParametersStoreRef,
+		v1alpha1.ParametersStoreRef{Name: "DB_PORT", Key: "port", Default: ptr("5432")},
+		v1alpha1.ParametersStoreRef{Name: "DB_OPTIONS", Key: "options", Optional: true},
+	)
+	r, cl, _ := newTestReconciler(t, append([]client.Object{parameterStore}, objs...)...)
+	return r, cl
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func keyStates(ps *v1alpha1.ParameterStore) map[string]string {
+	states := map[string]string{}
+	for _, k := range ps.Status.SSMStatus.Key {
+		states[k.Name] = k.State
+	}
+	return states
+}
+
+func TestFailurePolicyFail(t *testing.T) {
+	r, cl := failurePolicyReconciler(t, "")
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "database", Namespace: "default"}}
+
+	result, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, BackoffRequeueDelay, result.RequeueAfter)
+
+	assert.True(t, apierrors.IsNotFound(cl.Get(context.TODO(), req.NamespacedName, &corev1.Secret{})))
+
+	got := &v1alpha1.ParameterStore{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.Equal(t, map[string]string{
+		"DB_PASSWORD": v1alpha1.KeyStateFailed,
+		"DB_PORT":     v1alpha1.KeyStateDefaulted,
+		"DB_OPTIONS":  v1alpha1.KeyStateMissing,
+	}, keyStates(got))
+	assert.True(t, apimeta.IsStatusConditionFalse(got.Status.Conditions, v1alpha1.ConditionTypeSSMParamMissing))
+}
+
+func TestFailurePolicyPartial(t *testing.T) {
+	r, cl := failurePolicyReconciler(t, v1alpha1.FailurePolicyPartial)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "database", Namespace: "default"}}
+
+	result, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, BackoffRequeueDelay, result.RequeueAfter)
+
+	secret := &corev1.Secret{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, secret))
+	assert.Equal(t, map[string][]byte{"DB_USER": []byte("dbuser"), "DB_PORT": []byte("5432")}, secret.Data)
+	assert.Contains(t, secret.Annotations, "ssm.aws/DB_PASSWORD_error")
+	assert.NotContains(t, secret.Annotations, "ssm.aws/DB_PORT_error")
+
+	got := &v1alpha1.ParameterStore{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.Equal(t, map[string]string{
+		"DB_USER":     v1alpha1.KeyStateSynced,
+		"DB_PASSWORD": v1alpha1.KeyStateFailed,
+		"DB_PORT":     v1alpha1.KeyStateDefaulted,
+		"DB_OPTIONS":  v1alpha1.KeyStateMissing,
+	}, keyStates(got))
+	assert.Equal(t, int32(2), got.Status.SyncedKeys)
+	ready := apimeta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeReady)
+	assert.Equal(t, metav1.ConditionFalse, ready.Status)
+	assert.Equal(t, v1alpha1.KeyNotFoundReason, ready.Reason)
+}
+
+func TestFailurePolicyKeepLastKnown(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "default"},
+		Data:       map[string][]byte{"DB_PASSWORD": []byte("dbpassword")},
+	}
+	r, cl := failurePolicyReconciler(t, v1alpha1.FailurePolicyKeepLastKnown, secret)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "database", Namespace: "default"}}
+
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, secret))
+	assert.Equal(t, "dbpassword", string(secret.Data["DB_PASSWORD"]))
+	assert.Equal(t, "dbuser", string(secret.Data["DB_USER"]))
+
+	got := &v1alpha1.ParameterStore{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.Equal(t, v1alpha1.KeyStateLastKnown, keyStates(got)["DB_PASSWORD"])
+	assert.False(t, apimeta.IsStatusConditionTrue(got.Status.Conditions, v1alpha1.ConditionTypeReady))
+}
+
+func TestOptionalKeys(t *testing.T) {
+	r, cl := failurePolicyReconciler(t, "")
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "database", Namespace: "default"}}
+
+	parameterStore := &v1alpha1.ParameterStore{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, parameterStore))
+	refs := parameterStore.Spec.ValueFrom.ParametersStoreRef
+	parameterStore.Spec.ValueFrom.ParametersStoreRef = append(refs[:1], refs[2:]...)
+	assert.Nil(t, cl.Update(context.TODO(), parameterStore))
+
+	result, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, ctrl.Result{}, result)
+
+	secret := &corev1.Secret{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, secret))
+	assert.Equal(t, map[string][]byte{"DB_USER": []byte("dbuser"), "DB_PORT": []byte("5432")}, secret.Data)
+
+	got := &v1alpha1.ParameterStore{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.Equal(t, v1alpha1.KeyStateMissing, keyStates(got)["DB_OPTIONS"])
+	assert.True(t, apimeta.IsStatusConditionTrue(got.Status.Conditions, v1alpha1.ConditionTypeReady))
+}
+
+func TestFailurePolicyPartialUnnamedKeys(t *testing.T) {
+	values := map[string]string{"user": "dbuser"}
+	testutil.AppConfigServer(t, values)
+	parameterStore := testParameterStore()
+	parameterStore.Spec.FailurePolicy = v1alpha1.FailurePolicyPartial
+	parameterStore.Spec.ValueFrom.ParametersStoreRef = []v1alpha1.ParametersStoreRef{
+		{Key: "user"}, {Key: "app/password"}, {Key: "app/host"}, {Key: "app/options", Optional: true},
+	}
+	r, cl, req := newTestReconciler(t, parameterStore)
+
+	// The errors of unnamed keys are annotated with their key in the Secret.
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	secret := &corev1.Secret{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, secret))
+	assert.Contains(t, secret.Annotations, "ssm.aws/PASSWORD_error")
+	assert.Contains(t, secret.Annotations, "ssm.aws/HOST_error")
+	assert.NotContains(t, secret.Annotations, "ssm.aws/OPTIONS_error")
+	assert.NotContains(t, secret.Annotations, "ssm.aws/_error")
+
+	// Once the keys recover, their errors are removed.
+	values["app/password"] = "dbpassword"
+	values["app/host"] = "db"
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, secret))
+	assert.Equal(t, map[string][]byte{"USER": []byte("dbuser"), "PASSWORD": []byte("dbpassword"), "HOST": []byte("db")}, secret.Data)
+	for k := range secret.Annotations {
+		assert.NotContains(t, k, "_error")
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	ssmv1alpha1 "github.com/fr123k/az-app-config-operator/api/v1alpha1"
+)
+
+var (
+	lastSyncTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "parameterstore_last_sync_timestamp_seconds",
+		Help: "Unix time of the last successful sync of the ParameterStore.",
+	}, []string{"namespace", "name"})
+	syncedKeys = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "parameterstore_synced_keys",
+		Help: "Number of keys in the Secret of the ParameterStore.",
+	}, []string{"namespace", "name"})
+	secretReconciles = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "parameterstore_secret_reconciles_total",
+		Help: "Number of reconciles that wrote the Secret (write) or found it up to date (noop).",
+	}, []string{"result"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(lastSyncTimestamp, syncedKeys, secretReconciles)
+}
+
+// observeSync reports the last successful sync of the cr.
+func observeSync(cr *ssmv1alpha1.ParameterStore) {
+	staleness.observe(cr)
+	if cr.Status.LastSyncTime == nil {
+		return
+	}
+	lastSyncTimestamp.WithLabelValues(cr.Namespace, cr.Name).Set(float64(cr.Status.LastSyncTime.Unix()))
+	syncedKeys.WithLabelValues(cr.Namespace, cr.Name).Set(float64(cr.Status.SyncedKeys))
+}
+
+// forgetSync stops reporting a deleted cr.
+func forgetSync(key types.NamespacedName) {
+	staleness.forget(key)
+	lastSyncTimestamp.DeleteLabelValues(key.Namespace, key.Name)
+	syncedKeys.DeleteLabelValues(key.Namespace, key.Name)
+}
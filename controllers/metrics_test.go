@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcileMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Sync-Token", "id=value;sn=0")
+		_, _ = rw.Write([]byte(`{"etag": "etag", "key": "user", "value": "dbuser"}`))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("LOCAL_STACK_ENDPOINT", server.URL)
+	parameterStore := testParameterStore()
+	parameterStore.Name = "metrics"
+	parameterStore.Spec.ValueFrom.ParametersStoreRef = parameterStore.Spec.ValueFrom.ParametersStoreRef[:1]
+	r, cl, req := newTestReconciler(t, parameterStore)
+
+	writes, noops := testutil.ToFloat64(secretReconciles.WithLabelValues("write")), testutil.ToFloat64(secretReconciles.WithLabelValues("noop"))
+
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+
+	assert.Equal(t, writes+1, testutil.ToFloat64(secretReconciles.WithLabelValues("write")))
+	assert.Equal(t, noops+1, testutil.ToFloat64(secretReconciles.WithLabelValues("noop")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(syncedKeys.WithLabelValues("default", "metrics")))
+	assert.Greater(t, testutil.ToFloat64(lastSyncTimestamp.WithLabelValues("default", "metrics")), float64(0))
+
+	// The metrics of a deleted cr are removed.
+	assert.Nil(t, cl.Delete(context.TODO(), parameterStore))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.False(t, syncedKeys.DeleteLabelValues("default", "metrics"))
+	assert.False(t, lastSyncTimestamp.DeleteLabelValues("default", "metrics"))
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	ssmv1alpha1 "github.com/fr123k/az-app-config-operator/api/v1alpha1"
+)
+
+// ParameterStoreCRD is the name of the CustomResourceDefinition of the ParameterStore.
+const ParameterStoreCRD = "parameterstores.ssm.aws"
+
+// StorageVersionMigrator rewrites the ParameterStores stored in a former
+// version in the storage version of the CRD. Once all are rewritten, the former
+// versions are dropped from the stored versions of the CRD, so they can be
+// removed from the CRD in a later release.
+type StorageVersionMigrator struct {
+	client.Client
+	// Reader reads the CRD and the ParameterStores, which aren't cached by the
+	// manager or only partially.
+	Reader client.Reader
+	// Namespaces restricts the migration to the ParameterStores of the
+	// namespaces, all are migrated if empty. A restricted migrator doesn't see
+	// all ParameterStores, so it leaves the stored versions of the CRD as is.
+	Namespaces []string
+	// Interval is the time between the attempts of a failed migration.
+	Interval time.Duration
+}
+
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions/status,verbs=update
+
+// Start migrates the ParameterStores and retries until it succeeded or ctx is done.
+func (m *StorageVersionMigrator) Start(ctx context.Context) error {
+	log := logf.FromContext(ctx).WithName("storage-version-migrator")
+	err := wait.PollUntilContextCancel(ctx, m.Interval, true, func(ctx context.Context) (bool, error) {
+		if err := m.migrate(ctx); err != nil {
+			log.Error(err, "Failed to migrate ParameterStores to the storage version")
+			return false, nil
+		}
+		return true, nil
+	})
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func (m *StorageVersionMigrator) migrate(ctx context.Context) error {
+	log := logf.FromContext(ctx)
+
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := m.Reader.Get(ctx, client.ObjectKey{Name: ParameterStoreCRD}, crd); err != nil {
+		return err
+	}
+	var storage string
+	for _, v := range crd.Spec.Versions {
+		if v.Storage {
+			storage = v.Name
+		}
+	}
+	if slices.Equal(crd.Status.StoredVersions, []string{storage}) {
+		return nil
+	}
+
+	items, err := m.list(ctx)
+	if err != nil {
+		return err
+	}
+	var failed []string
+	for i := range items {
+		ps := &items[i]
+		// An empty patch doesn't change the object, but the API server writes
+		// it again in the storage version. The patch of the status subresource
+		// doesn't pass the admission webhooks, which would default the spec or
+		// reject it by the current validation or access policies.
+		err := m.Status().Patch(ctx, ps, client.RawPatch(types.MergePatchType, []byte("{}")))
+		if err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Failed to migrate ParameterStore to the storage version", "Namespace", ps.Namespace, "Name", ps.Name)
+			failed = append(failed, ps.Namespace+"/"+ps.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to migrate %d of %d ParameterStores: %s", len(failed), len(items), strings.Join(failed, ", "))
+	}
+
+	log.Info("Migrated ParameterStores to the storage version", "StorageVersion", storage, "StoredVersions", crd.Status.StoredVersions, "Count", len(items))
+	if len(m.Namespaces) > 0 {
+		log.Info("Stored versions of the CRD left to an operator watching all namespaces", "Namespaces", m.Namespaces)
+		return nil
+	}
+	crd.Status.StoredVersions = []string{storage}
+	return m.Status().Update(ctx, crd)
+}
+
+// list returns the ParameterStores of the namespaces of the migrator.
+func (m *StorageVersionMigrator) list(ctx context.Context) ([]ssmv1alpha1.ParameterStore, error) {
+	if len(m.Namespaces) == 0 {
+		list := &ssmv1alpha1.ParameterStoreList{}
+		if err := m.Reader.List(ctx, list); err != nil {
+			return nil, err
+		}
+		return list.Items, nil
+	}
+	var items []ssmv1alpha1.ParameterStore
+	for _, ns := range m.Namespaces {
+		list := &ssmv1alpha1.ParameterStoreList{}
+		if err := m.Reader.List(ctx, list, client.InNamespace(ns)); err != nil {
+			return nil, err
+		}
+		items = append(items, list.Items...)
+	}
+	return items, nil
+}
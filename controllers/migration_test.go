@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+)
+
+// migrationClient returns a client with two ParameterStores and their CRD
+// stored in two versions, patches of the rejected ParameterStore fail.
+func migrationClient(t *testing.T, patched *[]string, rejected string) (client.Client, *apiextensionsv1.CustomResourceDefinition) {
+	s := runtime.NewScheme()
+	assert.Nil(t, scheme.AddToScheme(s))
+	assert.Nil(t, apiextensionsv1.AddToScheme(s))
+	assert.Nil(t, v1alpha1.AddToScheme(s))
+
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: ParameterStoreCRD},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+			{Name: "v1alpha1", Served: true},
+			{Name: "v1beta1", Served: true, Storage: true},
+		}},
+		Status: apiextensionsv1.CustomResourceDefinitionStatus{StoredVersions: []string{"v1alpha1", "v1beta1"}},
+	}
+	cl := fake.NewClientBuilder().WithScheme(s).
+		WithObjects(crd,
+			&v1alpha1.ParameterStore{ObjectMeta: metav1.ObjectMeta{Name: "app1", Namespace: "default"}},
+			&v1alpha1.ParameterStore{ObjectMeta: metav1.ObjectMeta{Name: "app2", Namespace: "team"}},
+		).
+		WithStatusSubresource(crd, &v1alpha1.ParameterStore{}).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				t.Errorf("%s/%s patched through the admission webhooks", obj.GetNamespace(), obj.GetName())
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+			SubResourcePatch: func(ctx context.Context, c client.Client, subResource string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+				*patched = append(*patched, obj.GetNamespace()+"/"+obj.GetName())
+				if obj.GetName() == rejected {
+					return apierrors.NewForbidden(v1alpha1.GroupVersion.WithResource("parameterstores").GroupResource(), rejected, errors.New("denied"))
+				}
+				return c.SubResource(subResource).Patch(ctx, obj, patch, opts...)
+			},
+		}).
+		Build()
+	return cl, crd
+}
+
+func TestStorageVersionMigrator(t *testing.T) {
+	var patched []string
+	cl, crd := migrationClient(t, &patched, "app2")
+	m := &StorageVersionMigrator{Client: cl, Reader: cl}
+
+	// A rejected ParameterStore doesn't stop the migration of the others, but
+	// the stored versions are kept until all are migrated.
+	assert.ErrorContains(t, m.migrate(context.TODO()), "failed to migrate 1 of 2 ParameterStores: team/app2")
+	assert.ElementsMatch(t, []string{"default/app1", "team/app2"}, patched)
+	assert.Nil(t, cl.Get(context.TODO(), client.ObjectKeyFromObject(crd), crd))
+	assert.Equal(t, []string{"v1alpha1", "v1beta1"}, crd.Status.StoredVersions)
+
+	cl, crd = migrationClient(t, &patched, "")
+	m = &StorageVersionMigrator{Client: cl, Reader: cl}
+	patched = nil
+	assert.Nil(t, m.migrate(context.TODO()))
+	assert.ElementsMatch(t, []string{"default/app1", "team/app2"}, patched)
+	assert.Nil(t, cl.Get(context.TODO(), client.ObjectKeyFromObject(crd), crd))
+	assert.Equal(t, []string{"v1beta1"}, crd.Status.StoredVersions)
+
+	// Once migrated, nothing is written again.
+	patched = nil
+	assert.Nil(t, m.migrate(context.TODO()))
+	assert.Empty(t, patched)
+}
+
+func TestStorageVersionMigratorNamespaces(t *testing.T) {
+	var patched []string
+	cl, crd := migrationClient(t, &patched, "")
+	m := &StorageVersionMigrator{Client: cl, Reader: cl, Namespaces: []string{"team"}}
+
+	// A restricted migrator only migrates its namespaces and doesn't see the
+	// others, so it keeps the stored versions.
+	assert.Nil(t, m.migrate(context.TODO()))
+	assert.Equal(t, []string{"team/app2"}, patched)
+	assert.Nil(t, cl.Get(context.TODO(), client.ObjectKeyFromObject(crd), crd))
+	assert.Equal(t, []string{"v1alpha1", "v1beta1"}, crd.Status.StoredVersions)
+}
@@ -17,47 +17,82 @@ limitations under the License.
 package controllers
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"reflect"
+	"sort"
+	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	metav1ac "k8s.io/client-go/applyconfigurations/meta/v1"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/client-go/util/retry"
 	_ "k8s.io/client-go/util/workqueue"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	_ "sigs.k8s.io/controller-runtime/pkg/controller"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
-	ssmv1alpha1 "github.com/fr123k/aws-ssm-operator/api/v1alpha1"
+	ssmv1alpha1 "github.com/fr123k/az-app-config-operator/api/v1alpha1"
 
-	"github.com/fr123k/aws-ssm-operator/pkg/aws"
+	"github.com/fr123k/az-app-config-operator/pkg/azure"
 )
 
 // var log = logf.Log.WithName("parameterstore-controller")
 
+// FieldManager is the server-side apply field manager used for the Secrets
+// written by the operator. Only the fields applied under this manager are
+// owned by the operator, keys written by other tools are left untouched.
+const FieldManager = "az-app-config-operator"
+
+const (
+	// ContentHashAnnotation holds the hash of the Secret data applied by the operator.
+	ContentHashAnnotation = "aws-ssm-operator/content-hash"
+	// UpdatedAnnotation holds the time the Secret data was changed the last time.
+	UpdatedAnnotation = "aws-ssm-operator/updated"
+)
+
 // ParameterStoreReconciler reconciles a ParameterStore object
 type ParameterStoreReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 
-	SSMc *aws.SSMClient
+	AppConfig *azure.AppConfigClient
+	Recorder  events.EventRecorder
+	// NewAppConfig creates the clients of the stores set by spec.store, only
+	// the store of AppConfig is supported if nil.
+	NewAppConfig func(store string) (*azure.AppConfigClient, error)
+	// Stores are the stores spec.store may select, other stores aren't read
+	// so namespaces can't send the requests of the operator to any store.
+	Stores []string
+
+	events eventDeduper
+	stores storeClients
 }
 
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=ssm.aws,resources=parameterstores,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=ssm.aws,resources=parameterstores/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=ssm.aws,resources=parameterstores/finalizers,verbs=update
+//+kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -69,6 +104,13 @@ type ParameterStoreReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.11.0/pkg/reconcile
 func (r *ParameterStoreReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := startSpan(ctx, "ParameterStore.Reconcile", requestAttributes("ParameterStore", req)...)
+	result, err := r.reconcile(ctx, req)
+	endSpan(span, err)
+	return result, err
+}
+
+func (r *ParameterStoreReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := logf.FromContext(ctx)
 	reqLogger := log.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name)
 
@@ -82,165 +124,388 @@ func (r *ParameterStoreReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 			// Request object not found, could have been deleted after reconcile req.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
+			forgetSync(req.NamespacedName)
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the req.
 		return reconcile.Result{}, err
 	}
+	// The status is computed on the instance and patched once at the end.
+	original := instance.DeepCopy()
 
-	// Define a new Secret object
-	desired, err := r.newSecretForCR(instance)
+	// Check if this Secret already exists
+	current := &corev1.Secret{}
+	err = r.Get(ctx, req.NamespacedName, current)
+	if err != nil && !errors.IsNotFound(err) {
+		return reconcile.Result{}, err
+	}
+	exists := !errors.IsNotFound(err)
+
+	// The AppConfigAccessPolicies of the namespace are checked before reading
+	// the keys, and again with the label and content type of the read settings.
+	policies, err := ssmv1alpha1.AccessPoliciesFor(ctx, r.Client, instance.Namespace)
 	if err != nil {
-		var ssmStatus ssmv1alpha1.SSMStatus
+		return reconcile.Result{}, err
+	}
+	if err := policies.CheckSpec(&instance.Spec); err != nil {
+		return r.deny(ctx, original, instance, policies, err)
+	}
+
+	// Define a new Secret object, a partial error means that keys are missing
+	// but the Secret is written according to the failure policy.
+	desired, keys, partial := r.newSecretForCR(ctx, instance, current)
+	if err := policies.CheckKeys(instance.Spec.Store, keys); err != nil {
+		return r.deny(ctx, original, instance, policies, err)
+	}
+	apimeta.RemoveStatusCondition(&instance.Status.Conditions, ssmv1alpha1.ConditionTypeForbidden)
+	if instance.Spec.DryRun {
+		return r.dryRun(ctx, original, instance, current, desired, keys, partial)
+	}
+	// The changes of a previous dry-run are obsolete once the Secret is synced.
+	instance.Status.DryRun = nil
+	apimeta.RemoveStatusCondition(&instance.Status.Conditions, ssmv1alpha1.ConditionTypeDryRun)
+	if desired == nil {
+		err := partial
 		var conditionType string
 		// Update status.Nodes if needed
-		if ssmErr, ok := err.(*aws.SSMError); ok {
-			ks := make([]ssmv1alpha1.KeyStatus, len(ssmErr.ParameterErrors))
-			for i, e := range ssmErr.ParameterErrors {
-				ks[i] = ssmv1alpha1.KeyStatus{Name: e.Name, Error: e.Error()}
-			}
-			ssmStatus = ssmv1alpha1.SSMStatus{
-				Key: ks,
+		if ssmErr, ok := err.(*azure.SSMError); ok && len(ssmErr.ParameterErrors) > 0 {
+			instance.Status.SSMStatus = &ssmv1alpha1.SSMStatus{
+				Key: keys,
 			}
 			conditionType = ssmv1alpha1.ConditionTypeSSMParamMissing
 		} else {
-			ssmStatus = ssmv1alpha1.SSMStatus{
+			instance.Status.SSMStatus = &ssmv1alpha1.SSMStatus{
 				Error: err.Error(),
 			}
 			conditionType = ssmv1alpha1.ConditionTypeSSMError
 		}
-		if !reflect.DeepEqual(ssmStatus, instance.Status.SSMStatus) {
-			instance.Status.SSMStatus = &ssmStatus
-			err := r.Status().Update(ctx, instance)
-			if err != nil {
-				log.Error(err, "Failed to update ParameterStore status")
-				return reconcile.Result{}, err
-			}
-		}
-		{
-			readyCondition := metav1.Condition{
-				Status:             metav1.ConditionFalse,
-				Reason:             ssmv1alpha1.ReconciliationFailedReason,
-				Message:            err.Error(),
-				Type:               conditionType,
-				ObservedGeneration: instance.GetGeneration(),
-			}
-			apimeta.SetStatusCondition(&instance.Status.Conditions, readyCondition)
-			err := r.Status().Update(ctx, instance)
-			if err != nil {
-				log.Error(err, "Failed to update ParameterStore status")
-				return reconcile.Result{}, err
-			}
-		}
 
 		log.Error(err, "Failed to fetch SSM parameters status")
-		return reconcile.Result{}, err
-	} else {
-		instance.Status.SSMStatus = nil
+		return r.fail(ctx, original, instance, conditionType, err)
+	}
+	instance.Status.SSMStatus = &ssmv1alpha1.SSMStatus{Key: keys}
+
+	hash := contentHash(desired.Data)
+	if exists && current.Annotations[ContentHashAnnotation] == hash && dataContains(current.Data, desired.Data) {
+		reqLogger.Info("Secret is up to date", "desired.Namespace", *desired.Namespace, "desired.Name", *desired.Name)
+		resolveDrift(instance)
+		secretReconciles.WithLabelValues("noop").Inc()
+		if err := r.rollout(ctx, instance, *desired.Name, hash, false); err != nil {
+			log.Error(err, "Failed to rollout workloads")
+			return r.fail(ctx, original, instance, ssmv1alpha1.ConditionTypeReady, err)
+		}
+		return r.ready(ctx, original, instance, desired, partial)
 	}
 
-	// Set ParameterStore instance as the owner and controller
-	if err := controllerutil.SetControllerReference(instance, desired, r.Scheme); err != nil {
-		return reconcile.Result{}, err
+	// A Secret that diverged from the content applied before, or disappeared,
+	// was modified outside of the operator and is restored.
+	previousHash := current.Annotations[ContentHashAnnotation]
+	drifted := exists && previousHash == hash && !dataContains(current.Data, desired.Data)
+	if drifted {
+		r.recordDrift(ctx, instance, ssmv1alpha1.SecretModifiedReason, driftMessage(current, desired.Data))
+	} else if !exists && instance.Status.SecretStatus != nil {
+		r.recordDrift(ctx, instance, ssmv1alpha1.SecretDeletedReason,
+			fmt.Sprintf("Secret %s was deleted outside of the operator and restored", *desired.Name))
 	}
 
-	// Check if this Secret already exists
-	current := &corev1.Secret{}
-	err = r.Get(context.TODO(), types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, current)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			reqLogger.Info("Creating a new Secret", "desired.Namespace", desired.Namespace, "desired.Name", desired.Name)
-			err = r.Create(context.TODO(), desired)
+	// The data of an existing Secret changed if a value differs or keys were
+	// removed, which is only visible by the content hash applied before.
+	changed := exists && !drifted && (!dataContains(current.Data, desired.Data) || (previousHash != "" && previousHash != hash))
+
+	// The last changed time is only moved forward if the data really changes.
+	updated := time.Now().Format(time.RFC3339)
+	if exists && !changed && current.Annotations[UpdatedAnnotation] != "" {
+		updated = current.Annotations[UpdatedAnnotation]
+	}
+	desired.WithAnnotations(map[string]string{
+		ContentHashAnnotation: hash,
+		UpdatedAnnotation:     updated,
+	})
+
+	// Only claim the controller reference for Secrets created by the operator,
+	// a pre-existing Secret (e.g. managed by Helm) must not be garbage collected
+	// together with the ParameterStore.
+	if !exists || metav1.IsControlledBy(current, instance) {
+		ownerRef, err := r.ownerReference(instance)
+		if err != nil {
+			return reconcile.Result{}, err
 		}
-	} else {
-		reqLogger.Info("Updating an existing Secret", "desired.Namespace", desired.Namespace, "desired.Name", desired.Name)
-		err = r.Update(context.TODO(), desired)
+		desired.WithOwnerReferences(ownerRef)
 	}
 
+	// The changes are computed before the apply, which only knows the new data.
+	changes := diffData(current, desired.Data)
+
+	reqLogger.Info("Applying Secret", "desired.Namespace", *desired.Namespace, "desired.Name", *desired.Name)
+	applyOpts := []client.ApplyOption{client.FieldOwner(FieldManager)}
+	if drifted {
+		// The keys were applied by the operator before, whoever changed them
+		// took over their ownership.
+		applyOpts = append(applyOpts, client.ForceOwnership)
+	}
+	err = r.applySecret(ctx, desired, applyOpts...)
 	if err != nil {
-		return reconcile.Result{}, err
+		if errors.IsConflict(err) {
+			log.Error(err, "Secret keys are owned by an other field manager", "Secret.Name", *desired.Name)
+			return r.fail(ctx, original, instance, ssmv1alpha1.ConditionTypeFieldManagerConflict, err)
+		}
+		return r.fail(ctx, original, instance, ssmv1alpha1.ConditionTypeReady, err)
+	}
+	apimeta.RemoveStatusCondition(&instance.Status.Conditions, ssmv1alpha1.ConditionTypeFieldManagerConflict)
+	secretReconciles.WithLabelValues("write").Inc()
+	// The keys and settings responsible for a change are recorded, never the values.
+	if !exists || changed {
+		instance.Status.LastChange = secretChange(changes, keys, &original.Status)
+	}
+	if !exists && instance.Status.SecretStatus == nil {
+		r.event(instance, corev1.EventTypeNormal, ssmv1alpha1.SecretCreatedReason, "Create",
+			fmt.Sprintf("Secret %s created with %d keys", *desired.Name, len(desired.Data)))
+	} else if changed {
+		r.event(instance, corev1.EventTypeNormal, ssmv1alpha1.SecretUpdatedReason, "Update",
+			fmt.Sprintf("Secret %s updated: %s", *desired.Name, changeMessage(instance.Status.LastChange)))
 	}
 
-	// Update status.Nodes if needed
-	secretStatus := ssmv1alpha1.SecretStatus{
-		Name:      desired.Name,
-		Namespace: desired.Namespace,
+	// Workloads are only restarted if the data of an existing Secret changed.
+	if err := r.rollout(ctx, instance, *desired.Name, hash, changed); err != nil {
+		log.Error(err, "Failed to rollout workloads")
+		return r.fail(ctx, original, instance, ssmv1alpha1.ConditionTypeReady, err)
 	}
-	if !reflect.DeepEqual(secretStatus, instance.Status.SecretStatus) {
-		instance.Status.SecretStatus = &secretStatus
-		err := r.Status().Update(ctx, instance)
-		if err != nil {
-			log.Error(err, "Failed to update ParameterStore status")
-			return reconcile.Result{}, err
-		}
+
+	return r.ready(ctx, original, instance, desired, partial)
+}
+
+// ready records the applied Secret and the Ready condition in the status of the cr.
+// If keys are missing in the applied Secret the cr isn't ready until they are synced.
+func (r *ParameterStoreReconciler) ready(ctx context.Context, original, instance *ssmv1alpha1.ParameterStore, desired *corev1ac.SecretApplyConfiguration, partial error) (ctrl.Result, error) {
+	instance.Status.SecretStatus = &ssmv1alpha1.SecretStatus{
+		Name:      *desired.Name,
+		Namespace: *desired.Namespace,
+	}
+	instance.Status.SyncedKeys = int32(len(desired.Data))
+	instance.Status.LastSyncTime = &metav1.Time{Time: time.Now()}
+	if partial != nil {
+		return r.fail(ctx, original, instance, ssmv1alpha1.ConditionTypeSSMParamMissing, partial)
 	}
+	fresh(instance)
+	observeSync(instance)
 
 	readyCondition := metav1.Condition{
 		Status:             metav1.ConditionTrue,
 		Reason:             ssmv1alpha1.ReconciliationSucceededReason,
-		Message:            fmt.Sprintf("Secret %s in ready state", desired.Name),
+		Message:            fmt.Sprintf("Secret %s in ready state", *desired.Name),
 		Type:               ssmv1alpha1.ConditionTypeReady,
 		ObservedGeneration: instance.GetGeneration(),
 	}
 	apimeta.SetStatusCondition(&instance.Status.Conditions, readyCondition)
-	err = r.Status().Update(ctx, instance)
-	if err != nil {
-		log.Error(err, "Failed to update ParameterStore status")
+
+	// The values are synced again after the refresh interval, even if nothing
+	// in the cluster changed.
+	var result reconcile.Result
+	if instance.Spec.RefreshInterval != nil {
+		result.RequeueAfter = instance.Spec.RefreshInterval.Duration
+	}
+	return result, r.patchStatus(ctx, original, instance)
+}
+
+// fail sets the condition of the error and marks the cr as not ready. The
+// request is requeued after the status was patched depending on the class of the error.
+func (r *ParameterStoreReconciler) fail(ctx context.Context, original, instance *ssmv1alpha1.ParameterStore, conditionType string, err error) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	reason, result, requeueErr := classify(err)
+	r.failureEvent(instance, reason, err)
+	for _, t := range []string{conditionType, ssmv1alpha1.ConditionTypeReady} {
+		apimeta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+			Status:             metav1.ConditionFalse,
+			Reason:             reason,
+			Message:            err.Error(),
+			Type:               t,
+			ObservedGeneration: instance.GetGeneration(),
+		})
+	}
+	// Unless the Secret was synced partially, it holds the data of the last successful sync.
+	if instance.Status.LastSyncTime.Equal(original.Status.LastSyncTime) {
+		if err := r.stale(ctx, instance); err != nil {
+			log.Error(err, "Failed to remove stale Secret")
+			result, requeueErr = reconcile.Result{}, err
+		}
+	} else {
+		fresh(instance)
+	}
+	observeSync(instance)
+	if err := r.patchStatus(ctx, original, instance); err != nil {
 		return reconcile.Result{}, err
 	}
+	return result, requeueErr
+}
+
+// patchStatus writes the status computed during the reconcile with a single
+// patch. On a conflict the latest cr is fetched and the status patched again,
+// the status is owned by the operator so the computed one always wins.
+func (r *ParameterStoreReconciler) patchStatus(ctx context.Context, original, instance *ssmv1alpha1.ParameterStore) error {
+	log := logf.FromContext(ctx)
 
-	return reconcile.Result{}, nil
+	instance.Status.ObservedGeneration = instance.GetGeneration()
+	if apiequality.Semantic.DeepEqual(original.Status, instance.Status) {
+		return nil
+	}
+
+	ctx, span := startSpan(ctx, "ParameterStore.PatchStatus")
+	base := original
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		patched := base.DeepCopy()
+		patched.Status = instance.Status
+		err := r.Status().Patch(ctx, patched, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{}))
+		if errors.IsConflict(err) {
+			latest := &ssmv1alpha1.ParameterStore{}
+			if err := r.Get(ctx, client.ObjectKeyFromObject(instance), latest); err != nil {
+				return err
+			}
+			base = latest
+		}
+		return err
+	})
+	endSpan(span, err)
+	if err != nil {
+		log.Error(err, "Failed to update ParameterStore status")
+	}
+	return err
 }
 
-// newSecretForCR returns a Secret with the same name/namespace as the cr
-func (r *ParameterStoreReconciler) newSecretForCR(cr *ssmv1alpha1.ParameterStore) (*corev1.Secret, error) {
+// newSecretForCR returns the apply configuration of a Secret with the same name/namespace as the cr.
+// It only contains the fields owned by the operator, keys that are not part of it
+// but were applied before are removed by the server-side apply.
+// If keys of the ParametersStoreRef fail, the apply configuration is only
+// returned together with their error if the failure policy allows it.
+func (r *ParameterStoreReconciler) newSecretForCR(ctx context.Context, cr *ssmv1alpha1.ParameterStore, current *corev1.Secret) (*corev1ac.SecretApplyConfiguration, []ssmv1alpha1.KeyStatus, error) {
 	labels := map[string]string{
 		"app": cr.Name,
 	}
-	ref := cr.Spec.ValueFrom.ParameterStoreRef
-	var data1 = make(map[string]string)
-	if ref != nil {
-		var err *aws.SSMError
-		data1, err = r.SSMc.SSMParameterValueToSecret(*ref)
+	appConfig, err := r.appConfig(ctx, cr)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := Resolve(appConfig, cr, current)
+	if res == nil {
+		return nil, nil, err
+	}
+	if err != nil && (cr.Spec.FailurePolicy == "" || cr.Spec.FailurePolicy == ssmv1alpha1.FailurePolicyFail) {
+		return nil, res.Failed, err
+	}
+	if len(res.Collisions) > 0 {
+		r.event(cr, corev1.EventTypeWarning, ssmv1alpha1.CollisionDetectedReason, "Sync",
+			fmt.Sprintf("Several keys map to the same Secret key: %s", strings.Join(res.Collisions, ", ")))
+	}
 
-		if err != nil {
-			return nil, err
-		}
+	// StringData is write-only and can't be tracked by the field manager,
+	// so the values are applied as Data.
+	data := make(map[string][]byte, len(res.Data))
+	for k, v := range res.Data {
+		data[k] = []byte(v)
 	}
-	var data2 = make(map[string]string)
-	var anno = make(map[string]string)
 
-	if cr.Spec.ValueFrom.ParametersStoreRef != nil {
-		var err *aws.SSMError
-		data2, anno, err = r.SSMc.SSMParametersValueToSecret(cr.Spec.ValueFrom.ParametersStoreRef)
+	secret := corev1ac.Secret(cr.Name, cr.Namespace).
+		WithLabels(labels).
+		WithAnnotations(res.Annotations).
+		WithData(data)
+	return secret, res.Keys(), err
+}
 
-		if err != nil {
-			return nil, err
+// keyStatus returns the status of the synced keys sorted by their name.
+func keyStatus(params map[string]azure.Parameter) []ssmv1alpha1.KeyStatus {
+	ks := make([]ssmv1alpha1.KeyStatus, 0, len(params))
+	for _, p := range params {
+		s := ssmv1alpha1.KeyStatus{
+			Name:        p.Name,
+			Key:         p.Key,
+			Label:       p.Label,
+			ETag:        p.ETag,
+			ContentType: p.ContentType,
+			Locked:      p.Locked,
+			State:       ssmv1alpha1.KeyStateSynced,
+		}
+		if p.LastModified != nil {
+			s.LastModified = &metav1.Time{Time: *p.LastModified}
 		}
+		ks = append(ks, s)
+	}
+	sort.Slice(ks, func(i, j int) bool { return ks[i].Name < ks[j].Name })
+	return ks
+}
+
+// applySecret applies the Secret as the operator's field manager.
+func (r *ParameterStoreReconciler) applySecret(ctx context.Context, secret *corev1ac.SecretApplyConfiguration, opts ...client.ApplyOption) error {
+	ctx, span := startSpan(ctx, "Secret.Apply", attribute.String("k8s.name", *secret.Name))
+	err := r.Apply(ctx, secret, opts...)
+	endSpan(span, err)
+	return err
+}
+
+// ownerReference returns the controller owner reference pointing to the cr.
+func (r *ParameterStoreReconciler) ownerReference(cr *ssmv1alpha1.ParameterStore) (*metav1ac.OwnerReferenceApplyConfiguration, error) {
+	gvk, err := apiutil.GVKForObject(cr, r.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	return metav1ac.OwnerReference().
+		WithAPIVersion(gvk.GroupVersion().String()).
+		WithKind(gvk.Kind).
+		WithName(cr.Name).
+		WithUID(cr.UID).
+		WithController(true).
+		WithBlockOwnerDeletion(true), nil
+}
+
+// contentHash returns a deterministic hash of the Secret data.
+func contentHash(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write(data[k])
+		h.Write([]byte{0})
 	}
+	return hex.EncodeToString(h.Sum(nil))
+}
 
-	for k, v := range data1 {
-		data2[k] = v
+// dataContains reports whether all keys of desired are in current with the same value.
+func dataContains(current, desired map[string][]byte) bool {
+	for k, v := range desired {
+		c, ok := current[k]
+		if !ok || !bytes.Equal(c, v) {
+			return false
+		}
 	}
+	return true
+}
 
-	anno["aws-ssm-operator/updated"] = time.Now().Format(time.RFC3339)
-	return &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:        cr.Name,
-			Namespace:   cr.Namespace,
-			Labels:      labels,
-			Annotations: anno,
-		},
-		StringData: data2,
-	}, nil
+// secretForParameterStore maps a Secret to the ParameterStore with the same name.
+func secretForParameterStore(ctx context.Context, secret client.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: secret.GetName(), Namespace: secret.GetNamespace()}}}
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ParameterStoreReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Only Secrets applied by the operator are watched to restore them if they
+	// are modified or deleted. Shared Secrets have no controller reference, so
+	// they are mapped by name instead of using Owns.
+	appliedSecret := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		_, ok := o.GetAnnotations()[ContentHashAnnotation]
+		return ok
+	})
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&ssmv1alpha1.ParameterStore{}).
-		// WithOptions(controller.Options{RateLimiter: workqueue.NewItemExponentialFailureRateLimiter(1*time.Second, 10*time.Second)}).
 		//This ignores changes on the Custome Resource that were made outside of the Spec like Metadata or Status.
-		WithEventFilter(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{})).
+		For(&ssmv1alpha1.ParameterStore{}, builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{}))).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(secretForParameterStore), builder.WithPredicates(appliedSecret)).
+		Watches(&ssmv1alpha1.AppConfigAccessPolicy{}, handler.EnqueueRequestsFromMapFunc(r.parameterStoresForPolicy)).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.parameterStoresInNamespace), builder.WithPredicates(predicate.LabelChangedPredicate{})).
+		// WithOptions(controller.Options{RateLimiter: workqueue.NewItemExponentialFailureRateLimiter(1*time.Second, 10*time.Second)}).
 		Complete(r)
 }
@@ -4,13 +4,22 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 
-	"github.com/fr123k/aws-ssm-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/internal/testutil"
+	"github.com/fr123k/az-app-config-operator/pkg/azure"
 )
 
 func TestParameterStoreController(t *testing.T) {
@@ -41,3 +50,193 @@ func TestParameterStoreController(t *testing.T) {
 	}
 	fmt.Printf("%+v", parameterStoreList)
 }
+
+// testParameterStore returns the ParameterStore database syncing the keys user
+// and password into the keys DB_USER and DB_PASSWORD of its Secret.
+func testParameterStore() *v1alpha1.ParameterStore {
+	return &v1alpha1.ParameterStore{
+		ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "default", UID: "1234"},
+		Spec: v1alpha1.ParameterStoreSpec{
+			ValueFrom: v1alpha1.ValueFrom{
+				ParametersStoreRef: []v1alpha1.ParametersStoreRef{
+					{Name: "DB_USER", Key: "user"},
+					{Name: "DB_PASSWORD", Key: "password"},
+				},
+			},
+		},
+	}
+}
+
+// newTestReconciler returns a reconciler with a fake client holding the
+// objects, reading from the store of LOCAL_STACK_ENDPOINT. The request is the
+// one of the first object.
+func newTestReconciler(t *testing.T, objs ...client.Object) (*ParameterStoreReconciler, client.Client, ctrl.Request) {
+	t.Helper()
+	appConfig, err := azure.NewAppClient(nil)
+	assert.Nil(t, err)
+
+	s := runtime.NewScheme()
+	assert.Nil(t, scheme.AddToScheme(s))
+	assert.Nil(t, v1alpha1.AddToScheme(s))
+
+	cl := fake.NewClientBuilder().WithScheme(s).
+		WithObjects(objs...).
+		WithStatusSubresource(&v1alpha1.ParameterStore{}).
+		WithReturnManagedFields().
+		Build()
+	r := &ParameterStoreReconciler{Client: cl, Scheme: s, AppConfig: appConfig}
+	return r, cl, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(objs[0])}
+}
+
+func TestReconcileKeepsForeignSecretKeys(t *testing.T) {
+	testutil.AppConfigServer(t, map[string]string{"user": "dbuser", "password": "dbpassword"})
+	parameterStore := testParameterStore()
+	// A Secret created by an other tool e.g. helm.
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "default"},
+		Data:       map[string][]byte{"HELM_KEY": []byte("helm")},
+	}
+	r, cl, req := newTestReconciler(t, parameterStore, secret)
+
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+
+	got := &corev1.Secret{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.Equal(t, "helm", string(got.Data["HELM_KEY"]))
+	assert.Equal(t, "dbuser", string(got.Data["DB_USER"]))
+	assert.Equal(t, "dbpassword", string(got.Data["DB_PASSWORD"]))
+	assert.Empty(t, got.OwnerReferences)
+
+	// Dropping a key from the ParameterStore removes only that key.
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, parameterStore))
+	parameterStore.Spec.ValueFrom.ParametersStoreRef = parameterStore.Spec.ValueFrom.ParametersStoreRef[:1]
+	assert.Nil(t, cl.Update(context.TODO(), parameterStore))
+
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.Equal(t, "helm", string(got.Data["HELM_KEY"]))
+	assert.Equal(t, "dbuser", string(got.Data["DB_USER"]))
+	assert.NotContains(t, got.Data, "DB_PASSWORD")
+}
+
+func TestReconcileOwnsCreatedSecret(t *testing.T) {
+	testutil.AppConfigServer(t, map[string]string{"user": "dbuser", "password": "dbpassword"})
+	r, cl, req := newTestReconciler(t, testParameterStore())
+
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+
+	got := &corev1.Secret{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.Equal(t, "dbuser", string(got.Data["DB_USER"]))
+	assert.Len(t, got.OwnerReferences, 1)
+	assert.Equal(t, "ParameterStore", got.OwnerReferences[0].Kind)
+}
+
+func TestReconcileSkipsUnchangedSecret(t *testing.T) {
+	testutil.AppConfigServer(t, map[string]string{"user": "dbuser", "password": "dbpassword"})
+	r, cl, req := newTestReconciler(t, testParameterStore())
+
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+
+	first := &corev1.Secret{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, first))
+	assert.Equal(t, contentHash(map[string][]byte{"DB_USER": []byte("dbuser"), "DB_PASSWORD": []byte("dbpassword")}), first.Annotations[ContentHashAnnotation])
+	assert.NotEmpty(t, first.Annotations[UpdatedAnnotation])
+
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+
+	second := &corev1.Secret{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, second))
+	assert.Equal(t, first.ResourceVersion, second.ResourceVersion)
+	assert.Equal(t, first.Annotations, second.Annotations)
+}
+
+func TestContentHash(t *testing.T) {
+	a := contentHash(map[string][]byte{"A": []byte("1"), "B": []byte("2")})
+	b := contentHash(map[string][]byte{"B": []byte("2"), "A": []byte("1")})
+	c := contentHash(map[string][]byte{"A": []byte("12")})
+
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, c)
+}
+
+func TestReconcileKeyStatus(t *testing.T) {
+	testutil.AppConfigServer(t, map[string]string{"user": "dbuser", "password": "dbpassword"})
+	parameterStore := testParameterStore()
+	r, cl, req := newTestReconciler(t, parameterStore)
+
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, parameterStore))
+	assert.NotNil(t, parameterStore.Status.SSMStatus)
+	keys := parameterStore.Status.SSMStatus.Key
+	assert.Len(t, keys, 2)
+	assert.Equal(t, "DB_PASSWORD", keys[0].Name)
+	assert.Equal(t, "password", keys[0].Key)
+	assert.Equal(t, "DB_USER", keys[1].Name)
+	assert.Equal(t, "user", keys[1].Key)
+	assert.Equal(t, "4f6dd610dd5e4deebc7fbaef685fb903", keys[1].ETag)
+	assert.Equal(t, "2017-12-05T02:41:26Z", keys[1].LastModified.UTC().Format(time.RFC3339))
+	assert.False(t, keys[1].Locked)
+	assert.Empty(t, keys[1].Error)
+}
+
+func TestReconcilePatchesStatusOnce(t *testing.T) {
+	testutil.AppConfigServer(t, map[string]string{"user": "dbuser", "password": "dbpassword"})
+	parameterStore := testParameterStore()
+	parameterStore.Generation = 2
+	r, cl, req := newTestReconciler(t, parameterStore)
+
+	statusWrites := 0
+	r.Client = interceptor.NewClient(cl.(client.WithWatch), interceptor.Funcs{
+		SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+			statusWrites++
+			return c.SubResource(subResourceName).Patch(ctx, obj, patch, opts...)
+		},
+		SubResourceUpdate: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+			statusWrites++
+			return c.SubResource(subResourceName).Update(ctx, obj, opts...)
+		},
+	})
+
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, statusWrites)
+
+	got := &v1alpha1.ParameterStore{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.Equal(t, got.Generation, got.Status.ObservedGeneration)
+	assert.Equal(t, int32(2), got.Status.SyncedKeys)
+	assert.NotNil(t, got.Status.LastSyncTime)
+	assert.True(t, apimeta.IsStatusConditionTrue(got.Status.Conditions, v1alpha1.ConditionTypeReady))
+}
+
+func TestPatchStatusRetriesOnConflict(t *testing.T) {
+	testutil.AppConfigServer(t, nil)
+	parameterStore := testParameterStore()
+	r, cl, _ := newTestReconciler(t, parameterStore)
+
+	original := &v1alpha1.ParameterStore{}
+	assert.Nil(t, cl.Get(context.TODO(), client.ObjectKeyFromObject(parameterStore), original))
+
+	// The cr is changed by someone else after it was read.
+	changed := original.DeepCopy()
+	changed.Labels = map[string]string{"app": "database"}
+	assert.Nil(t, cl.Update(context.TODO(), changed))
+
+	instance := original.DeepCopy()
+	instance.Status.SecretStatus = &v1alpha1.SecretStatus{Name: "database", Namespace: "default"}
+	assert.Nil(t, r.patchStatus(context.TODO(), original, instance))
+
+	got := &v1alpha1.ParameterStore{}
+	assert.Nil(t, cl.Get(context.TODO(), client.ObjectKeyFromObject(parameterStore), got))
+	assert.Equal(t, "database", got.Status.SecretStatus.Name)
+	assert.Equal(t, "database", got.Labels["app"])
+}
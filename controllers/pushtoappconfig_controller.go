@@ -0,0 +1,431 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ssmv1alpha1 "github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/pkg/azure"
+)
+
+// PushFinalizer guards the deletion of the settings pushed by a PushToAppConfig.
+const PushFinalizer = "ssm.aws/push-to-app-config"
+
+const secretRefIndex = "spec.secretRef.name"
+
+// PushToAppConfigReconciler reconciles a PushToAppConfig object
+type PushToAppConfigReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	AppConfig *azure.AppConfigClient
+	// KeyVaultDNSSuffixes are the DNS suffixes of the Key Vaults PushToAppConfigs
+	// may write to, azure.DefaultKeyVaultDNSSuffixes if empty.
+	KeyVaultDNSSuffixes []string
+
+	mu        sync.Mutex
+	keyVaults map[string]*azure.KeyVaultClient
+}
+
+//+kubebuilder:rbac:groups=ssm.aws,resources=pushtoappconfigs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=ssm.aws,resources=pushtoappconfigs/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=ssm.aws,resources=pushtoappconfigs/finalizers,verbs=update
+
+// Reconcile pushes the selected keys of the referenced Secret into the App Configuration store.
+func (r *PushToAppConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := startSpan(ctx, "PushToAppConfig.Reconcile", requestAttributes("PushToAppConfig", req)...)
+	result, err := r.reconcile(ctx, req)
+	endSpan(span, err)
+	return result, err
+}
+
+func (r *PushToAppConfigReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+	reqLogger := log.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name)
+
+	reqLogger.Info("Reconciling PushToAppConfig")
+
+	instance := &ssmv1alpha1.PushToAppConfig{}
+	err := r.Get(ctx, req.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	if !instance.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, r.finalize(ctx, instance)
+	}
+
+	if !controllerutil.ContainsFinalizer(instance, PushFinalizer) {
+		controllerutil.AddFinalizer(instance, PushFinalizer)
+		if err := r.Update(ctx, instance); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	// The operator sends its Azure credential to the Key Vault.
+	if instance.Spec.KeyVault != nil {
+		if err := azure.CheckVaultURL(instance.Spec.KeyVault.VaultURL, r.KeyVaultDNSSuffixes); err != nil {
+			reqLogger.Error(err, "Refusing to push to the Key Vault")
+			if err := r.updateStatus(ctx, instance, instance.Status.Keys, ssmv1alpha1.ConditionTypePushError, err.Error()); err != nil {
+				return reconcile.Result{}, err
+			}
+			return reconcile.Result{}, reconcile.TerminalError(err)
+		}
+	}
+
+	secret := &corev1.Secret{}
+	err = r.Get(ctx, types.NamespacedName{Name: instance.Spec.SecretRef.Name, Namespace: instance.Namespace}, secret)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+		// The Secret watch triggers the next reconcile once the Secret exists.
+		reqLogger.Info("Secret not found", "Secret.Name", instance.Spec.SecretRef.Name)
+		return reconcile.Result{}, r.updateStatus(ctx, instance, instance.Status.Keys, ssmv1alpha1.ConditionTypeSecretMissing,
+			fmt.Sprintf("Secret %s not found", instance.Spec.SecretRef.Name))
+	}
+
+	// Only the settings the AppConfigAccessPolicies of the namespace allow to
+	// write are pushed, or deleted.
+	policies, err := ssmv1alpha1.AccessPoliciesFor(ctx, r.Client, instance.Namespace)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	contentType := instance.ContentType()
+
+	previous := make(map[string]ssmv1alpha1.PushedKeyStatus, len(instance.Status.Keys))
+	for _, ks := range instance.Status.Keys {
+		previous[settingID(ks.Key, ks.Label)] = ks
+	}
+
+	keys := make([]ssmv1alpha1.PushedKeyStatus, 0, len(instance.Spec.Data))
+	pushed := make(map[string]bool, len(instance.Spec.Data))
+	var conflicts, failures int
+	var denied []ssmv1alpha1.PushedKeyStatus
+	for _, data := range instance.Spec.Data {
+		ks := ssmv1alpha1.PushedKeyStatus{SecretKey: data.SecretKey}
+		ks.Key, ks.Label, err = instance.SettingKey(data)
+		if err != nil {
+			ks.Error = err.Error()
+			keys = append(keys, ks)
+			failures++
+			continue
+		}
+		id := settingID(ks.Key, ks.Label)
+		pushed[id] = true
+
+		if !policies.AllowsPush(ks, contentType) {
+			ks.Error = (&ssmv1alpha1.AccessDeniedError{Namespace: instance.Namespace, Denied: []string{ks.Key}, Write: true}).Error()
+			ks.ETag = previous[id].ETag
+			keys = append(keys, ks)
+			denied = append(denied, ks)
+			continue
+		}
+
+		value, ok := secret.Data[data.SecretKey]
+		if !ok {
+			ks.Error = fmt.Sprintf("key %s not found in Secret %s", data.SecretKey, secret.Name)
+			ks.ETag = previous[id].ETag
+			keys = append(keys, ks)
+			failures++
+			continue
+		}
+
+		etag, conflict, err := r.push(ctx, instance, ks.Key, ks.Label, string(value), previous[id])
+		if err != nil {
+			reqLogger.Error(err, "Failed to push setting", "Key", ks.Key, "Label", ks.Label)
+			ks.Error = err.Error()
+			ks.ETag = previous[id].ETag
+			if conflict {
+				conflicts++
+			} else {
+				failures++
+			}
+		} else {
+			ks.ETag = string(etag)
+		}
+		keys = append(keys, ks)
+	}
+
+	// Settings that are no longer part of the spec are removed like on deletion.
+	if instance.Spec.DeletionPolicy == ssmv1alpha1.DeletionPolicyDelete {
+		for id, ks := range previous {
+			if pushed[id] || !policies.AllowsPush(ks, contentType) {
+				continue
+			}
+			if err := r.deleteSetting(ctx, ks); err != nil {
+				return reconcile.Result{}, err
+			}
+		}
+	}
+
+	switch {
+	case len(denied) > 0:
+		deniedErr := policies.CheckPush(denied, contentType)
+		reqLogger.Error(deniedErr, "Keys denied by the AppConfigAccessPolicies")
+		err = r.updateStatus(ctx, instance, keys, ssmv1alpha1.ConditionTypeForbidden, deniedErr.Error())
+	case conflicts > 0:
+		err = r.updateStatus(ctx, instance, keys, ssmv1alpha1.ConditionTypePushConflict,
+			fmt.Sprintf("%d settings were changed outside of the operator", conflicts))
+	case failures > 0:
+		err = r.updateStatus(ctx, instance, keys, ssmv1alpha1.ConditionTypePushError,
+			fmt.Sprintf("%d settings could not be pushed", failures))
+	default:
+		err = r.updateStatus(ctx, instance, keys, "", "")
+	}
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if failures > 0 {
+		return reconcile.Result{}, fmt.Errorf("failed to push %d settings", failures)
+	}
+	return reconcile.Result{}, nil
+}
+
+// push writes the value to the setting using the ETag of the previous push to
+// detect changes made by someone else. It reports a conflict if the setting
+// can't be written because of the ConflictPolicy.
+func (r *PushToAppConfigReconciler) push(ctx context.Context, cr *ssmv1alpha1.PushToAppConfig, key, label, value string, previous ssmv1alpha1.PushedKeyStatus) (azcore.ETag, bool, error) {
+	contentType := cr.ContentType()
+	if cr.Spec.KeyVault != nil {
+		kv, err := r.keyVault(cr.Spec.KeyVault.VaultURL)
+		if err != nil {
+			return "", false, err
+		}
+		ref, ssmErr := kv.WithContext(ctx).SetSecret(azure.SecretName(key), value)
+		if ssmErr != nil {
+			return "", false, ssmErr
+		}
+		value = ref
+	}
+
+	current, ssmErr := r.AppConfig.WithContext(ctx).GetSetting(key, label)
+	if ssmErr != nil {
+		return "", false, ssmErr
+	}
+
+	overwrite := cr.Spec.ConflictPolicy == ssmv1alpha1.ConflictPolicyOverwrite
+	var etag *azcore.ETag
+	if current != nil {
+		if current.ETag == nil {
+			// Without an ETag the setting can't be written safely, whatever the ConflictPolicy.
+			return "", true, fmt.Errorf("setting %s has no ETag", key)
+		}
+		if current.Value != nil && *current.Value == value && stringValue(current.ContentType) == contentType {
+			return *current.ETag, false, nil
+		}
+		if !overwrite && (previous.ETag == "" || azcore.ETag(previous.ETag) != *current.ETag) {
+			return "", true, fmt.Errorf("setting %s was changed outside of the operator", key)
+		}
+		etag = current.ETag
+	}
+
+	newETag, ssmErr := r.AppConfig.WithContext(ctx).SetSetting(key, label, value, contentType, etag)
+	if ssmErr != nil {
+		// The setting was changed between reading and writing it.
+		return "", azure.IsPreconditionFailed(ssmErr.Err), ssmErr
+	}
+	return newETag, false, nil
+}
+
+// finalize deletes the pushed settings if requested and releases the PushToAppConfig.
+func (r *PushToAppConfigReconciler) finalize(ctx context.Context, cr *ssmv1alpha1.PushToAppConfig) error {
+	if !controllerutil.ContainsFinalizer(cr, PushFinalizer) {
+		return nil
+	}
+	if cr.Spec.DeletionPolicy == ssmv1alpha1.DeletionPolicyDelete {
+		policies, err := ssmv1alpha1.AccessPoliciesFor(ctx, r.Client, cr.Namespace)
+		if err != nil {
+			return err
+		}
+		for _, ks := range cr.Status.Keys {
+			if !policies.AllowsPush(ks, cr.ContentType()) {
+				logf.FromContext(ctx).Info("Keep setting the AppConfigAccessPolicies don't allow to delete", "Key", ks.Key, "Label", ks.Label)
+				continue
+			}
+			if err := r.deleteSetting(ctx, ks); err != nil {
+				return err
+			}
+		}
+	}
+	controllerutil.RemoveFinalizer(cr, PushFinalizer)
+	return r.Update(ctx, cr)
+}
+
+// deleteSetting deletes a pushed setting unless it was changed by someone else since.
+func (r *PushToAppConfigReconciler) deleteSetting(ctx context.Context, ks ssmv1alpha1.PushedKeyStatus) error {
+	log := logf.FromContext(ctx)
+	if ks.ETag == "" {
+		return nil
+	}
+	if err := r.AppConfig.WithContext(ctx).DeleteSetting(ks.Key, ks.Label, azcore.ETag(ks.ETag)); err != nil {
+		if azure.IsPreconditionFailed(err.Err) {
+			log.Info("Keep setting that was changed outside of the operator", "Key", ks.Key, "Label", ks.Label)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *PushToAppConfigReconciler) updateStatus(ctx context.Context, cr *ssmv1alpha1.PushToAppConfig, keys []ssmv1alpha1.PushedKeyStatus, conditionType, message string) error {
+	log := logf.FromContext(ctx)
+
+	cr.Status.Keys = keys
+	for _, t := range []string{ssmv1alpha1.ConditionTypeSecretMissing, ssmv1alpha1.ConditionTypeForbidden, ssmv1alpha1.ConditionTypePushConflict, ssmv1alpha1.ConditionTypePushError} {
+		if t != conditionType {
+			apimeta.RemoveStatusCondition(&cr.Status.Conditions, t)
+		}
+	}
+
+	readyCondition := metav1.Condition{
+		Status:             metav1.ConditionTrue,
+		Reason:             ssmv1alpha1.ReconciliationSucceededReason,
+		Message:            fmt.Sprintf("%d settings pushed", len(keys)),
+		Type:               ssmv1alpha1.ConditionTypeReady,
+		ObservedGeneration: cr.GetGeneration(),
+	}
+	if conditionType != "" {
+		reason := ssmv1alpha1.ReconciliationFailedReason
+		if conditionType == ssmv1alpha1.ConditionTypeForbidden {
+			reason = ssmv1alpha1.AccessDeniedReason
+		}
+		apimeta.SetStatusCondition(&cr.Status.Conditions, metav1.Condition{
+			Status:             metav1.ConditionFalse,
+			Reason:             reason,
+			Message:            message,
+			Type:               conditionType,
+			ObservedGeneration: cr.GetGeneration(),
+		})
+		readyCondition.Status = metav1.ConditionFalse
+		readyCondition.Reason = ssmv1alpha1.ReconciliationFailedReason
+		readyCondition.Message = message
+	}
+	apimeta.SetStatusCondition(&cr.Status.Conditions, readyCondition)
+
+	if err := r.Status().Update(ctx, cr); err != nil {
+		log.Error(err, "Failed to update PushToAppConfig status")
+		return err
+	}
+	return nil
+}
+
+func (r *PushToAppConfigReconciler) keyVault(vaultURL string) (*azure.KeyVaultClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if kv, ok := r.keyVaults[vaultURL]; ok {
+		return kv, nil
+	}
+	kv, err := azure.NewKeyVaultClient(vaultURL)
+	if err != nil {
+		return nil, err
+	}
+	if r.keyVaults == nil {
+		r.keyVaults = make(map[string]*azure.KeyVaultClient)
+	}
+	r.keyVaults[vaultURL] = kv
+	return kv, nil
+}
+
+func settingID(key, label string) string {
+	return key + "\x00" + label
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// pushesForSecret maps a Secret to the PushToAppConfigs referencing it.
+func (r *PushToAppConfigReconciler) pushesForSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	list := &ssmv1alpha1.PushToAppConfigList{}
+	if err := r.List(ctx, list, client.InNamespace(secret.GetNamespace()), client.MatchingFields{secretRefIndex: secret.GetName()}); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list PushToAppConfig for Secret", "Secret.Name", secret.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, len(list.Items))
+	for i, item := range list.Items {
+		requests[i] = reconcile.Request{NamespacedName: types.NamespacedName{Name: item.Name, Namespace: item.Namespace}}
+	}
+	return requests
+}
+
+// pushesForPolicy maps a changed AppConfigAccessPolicy to all PushToAppConfigs,
+// its namespace selector may select any namespace.
+func (r *PushToAppConfigReconciler) pushesForPolicy(ctx context.Context, _ client.Object) []reconcile.Request {
+	return r.pushes(ctx)
+}
+
+// pushesInNamespace maps a Namespace whose labels changed to its PushToAppConfigs.
+func (r *PushToAppConfigReconciler) pushesInNamespace(ctx context.Context, ns client.Object) []reconcile.Request {
+	return r.pushes(ctx, client.InNamespace(ns.GetName()))
+}
+
+func (r *PushToAppConfigReconciler) pushes(ctx context.Context, opts ...client.ListOption) []reconcile.Request {
+	list := &ssmv1alpha1.PushToAppConfigList{}
+	if err := r.List(ctx, list, opts...); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list the PushToAppConfigs to check their access")
+		return nil
+	}
+	requests := make([]reconcile.Request, len(list.Items))
+	for i, item := range list.Items {
+		requests[i] = reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&item)}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PushToAppConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := mgr.GetFieldIndexer().IndexField(context.Background(), &ssmv1alpha1.PushToAppConfig{}, secretRefIndex, func(o client.Object) []string {
+		return []string{o.(*ssmv1alpha1.PushToAppConfig).Spec.SecretRef.Name}
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&ssmv1alpha1.PushToAppConfig{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.pushesForSecret)).
+		Watches(&ssmv1alpha1.AppConfigAccessPolicy{}, handler.EnqueueRequestsFromMapFunc(r.pushesForPolicy)).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.pushesInNamespace), builder.WithPredicates(predicate.LabelChangedPredicate{})).
+		Complete(r)
+}
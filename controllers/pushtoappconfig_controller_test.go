@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/pkg/azure"
+)
+
+type fakeSetting struct {
+	Key         string  `json:"key"`
+	Label       *string `json:"label"`
+	Value       string  `json:"value"`
+	ContentType *string `json:"content_type"`
+	ETag        string  `json:"etag,omitempty"`
+}
+
+// fakeAppConfig is an in-memory App Configuration store supporting ETag preconditions.
+type fakeAppConfig struct {
+	mu       sync.Mutex
+	settings map[string]*fakeSetting
+	version  int
+}
+
+func newFakeAppConfig(t *testing.T) *fakeAppConfig {
+	store := &fakeAppConfig{settings: make(map[string]*fakeSetting)}
+	server := httptest.NewServer(http.HandlerFunc(store.serveHTTP))
+	t.Cleanup(server.Close)
+	t.Setenv("LOCAL_STACK_ENDPOINT", server.URL)
+	return store
+}
+
+func (f *fakeAppConfig) set(key, label, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.version++
+	f.settings[settingID(key, label)] = &fakeSetting{Key: key, Label: &label, Value: value, ETag: fmt.Sprintf("etag-%d", f.version)}
+}
+
+func (f *fakeAppConfig) get(key, label string) *fakeSetting {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.settings[settingID(key, label)]
+}
+
+func (f *fakeAppConfig) serveHTTP(rw http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	rw.Header().Set("Sync-Token", "id=value;sn=0")
+	key := strings.TrimPrefix(req.URL.Path, "/kv/")
+	label := req.URL.Query().Get("label")
+	id := settingID(key, label)
+	current := f.settings[id]
+
+	ifMatch := strings.Trim(req.Header.Get("If-Match"), `"`)
+	ifNoneMatch := req.Header.Get("If-None-Match")
+	if (ifMatch != "" && (current == nil || current.ETag != ifMatch)) || (ifNoneMatch == "*" && current != nil) {
+		rw.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		if current == nil {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+	case http.MethodPut:
+		setting := &fakeSetting{}
+		_ = json.NewDecoder(req.Body).Decode(setting)
+		f.version++
+		setting.Key, setting.Label, setting.ETag = key, &label, fmt.Sprintf("etag-%d", f.version)
+		f.settings[id] = setting
+		current = setting
+	case http.MethodDelete:
+		delete(f.settings, id)
+		if current == nil {
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	_ = json.NewEncoder(rw).Encode(current)
+}
+
+func pushReconciler(t *testing.T, objs ...client.Object) (*PushToAppConfigReconciler, client.Client) {
+	appConfig, err := azure.NewAppClient(nil)
+	assert.Nil(t, err)
+
+	s := runtime.NewScheme()
+	assert.Nil(t, scheme.AddToScheme(s))
+	assert.Nil(t, v1alpha1.AddToScheme(s))
+
+	cl := fake.NewClientBuilder().WithScheme(s).
+		WithObjects(objs...).
+		WithStatusSubresource(&v1alpha1.PushToAppConfig{}).
+		Build()
+	return &PushToAppConfigReconciler{Client: cl, Scheme: s, AppConfig: appConfig}, cl
+}
+
+func pushToAppConfig(conflictPolicy, deletionPolicy string) *v1alpha1.PushToAppConfig {
+	return &v1alpha1.PushToAppConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "push", Namespace: "default"},
+		Spec: v1alpha1.PushToAppConfigSpec{
+			SecretRef: v1alpha1.SecretReference{Name: "database"},
+			Data: []v1alpha1.PushData{
+				{SecretKey: "user"},
+				{SecretKey: "password", Key: "/shared/dbpassword"},
+			},
+			KeyTemplate:    "/{{ .Namespace }}/{{ .SecretName }}/{{ .SecretKey }}",
+			LabelTemplate:  "prod",
+			ConflictPolicy: conflictPolicy,
+			DeletionPolicy: deletionPolicy,
+		},
+	}
+}
+
+func databaseSecret() *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "default"},
+		Data:       map[string][]byte{"user": []byte("dbuser"), "password": []byte("dbpassword")},
+	}
+}
+
+func TestPushToAppConfig(t *testing.T) {
+	store := newFakeAppConfig(t)
+	r, cl := pushReconciler(t, pushToAppConfig(v1alpha1.ConflictPolicyFail, v1alpha1.DeletionPolicyDelete), databaseSecret())
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "push", Namespace: "default"}}
+
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "dbuser", store.get("/default/database/user", "prod").Value)
+	assert.Equal(t, "dbpassword", store.get("/shared/dbpassword", "prod").Value)
+
+	got := &v1alpha1.PushToAppConfig{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.Contains(t, got.Finalizers, PushFinalizer)
+	assert.Len(t, got.Status.Keys, 2)
+	assert.Equal(t, store.get("/default/database/user", "prod").ETag, got.Status.Keys[0].ETag)
+	assert.True(t, apimeta.IsStatusConditionTrue(got.Status.Conditions, v1alpha1.ConditionTypeReady))
+
+	// Unchanged values are not written again.
+	etag := store.get("/default/database/user", "prod").ETag
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, etag, store.get("/default/database/user", "prod").ETag)
+
+	// Deleting the PushToAppConfig deletes the pushed settings.
+	assert.Nil(t, cl.Delete(context.TODO(), got))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Nil(t, store.get("/default/database/user", "prod"))
+	assert.Nil(t, store.get("/shared/dbpassword", "prod"))
+}
+
+func TestPushToAppConfigConflict(t *testing.T) {
+	store := newFakeAppConfig(t)
+	store.set("/shared/dbpassword", "prod", "owned by someone else")
+	r, cl := pushReconciler(t, pushToAppConfig(v1alpha1.ConflictPolicyFail, v1alpha1.DeletionPolicyRetain), databaseSecret())
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "push", Namespace: "default"}}
+
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "owned by someone else", store.get("/shared/dbpassword", "prod").Value)
+	assert.Equal(t, "dbuser", store.get("/default/database/user", "prod").Value)
+
+	got := &v1alpha1.PushToAppConfig{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.NotEmpty(t, got.Status.Keys[1].Error)
+	assert.NotNil(t, apimeta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypePushConflict))
+	assert.False(t, apimeta.IsStatusConditionTrue(got.Status.Conditions, v1alpha1.ConditionTypeReady))
+
+	// Overwrite takes over the setting.
+	got.Spec.ConflictPolicy = v1alpha1.ConflictPolicyOverwrite
+	assert.Nil(t, cl.Update(context.TODO(), got))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, "dbpassword", store.get("/shared/dbpassword", "prod").Value)
+}
+
+func TestPushToAppConfigWithoutETag(t *testing.T) {
+	store := newFakeAppConfig(t)
+	store.set("/shared/dbpassword", "prod", "dbpassword")
+	store.get("/shared/dbpassword", "prod").ETag = ""
+	r, cl := pushReconciler(t, pushToAppConfig(v1alpha1.ConflictPolicyOverwrite, v1alpha1.DeletionPolicyRetain), databaseSecret())
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "push", Namespace: "default"}}
+
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+
+	got := &v1alpha1.PushToAppConfig{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.Contains(t, got.Status.Keys[1].Error, "has no ETag")
+	assert.NotNil(t, apimeta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypePushConflict))
+}
+
+func TestPushToAppConfigInvalidVaultURL(t *testing.T) {
+	store := newFakeAppConfig(t)
+	cr := pushToAppConfig(v1alpha1.ConflictPolicyFail, v1alpha1.DeletionPolicyRetain)
+	cr.Spec.KeyVault = &v1alpha1.KeyVaultRef{VaultURL: "https://attacker.example.com"}
+	r, cl := pushReconciler(t, cr, databaseSecret())
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "push", Namespace: "default"}}
+
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.ErrorIs(t, err, reconcile.TerminalError(nil))
+	assert.Nil(t, store.get("/default/database/user", "prod"))
+
+	got := &v1alpha1.PushToAppConfig{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	cond := apimeta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypePushError)
+	assert.NotNil(t, cond)
+	assert.Contains(t, cond.Message, "invalid Key Vault URL")
+}
+
+func TestPushToAppConfigAccessPolicies(t *testing.T) {
+	store := newFakeAppConfig(t)
+	store.set("/shared/dbpassword", "prod", "owned by someone else")
+	policy := &v1alpha1.AppConfigAccessPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: v1alpha1.AppConfigAccessPolicySpec{
+			Rules: []v1alpha1.AccessRule{
+				{KeyPrefixes: []string{"/default/"}, Write: true},
+				{KeyPrefixes: []string{"/shared/"}},
+			},
+		},
+	}
+	r, cl := pushReconciler(t, pushToAppConfig(v1alpha1.ConflictPolicyOverwrite, v1alpha1.DeletionPolicyDelete), databaseSecret(),
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}, policy)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "push", Namespace: "default"}}
+
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+
+	// Reading /shared/ doesn't allow to overwrite it.
+	assert.Equal(t, "dbuser", store.get("/default/database/user", "prod").Value)
+	assert.Equal(t, "owned by someone else", store.get("/shared/dbpassword", "prod").Value)
+
+	got := &v1alpha1.PushToAppConfig{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.Contains(t, got.Status.Keys[1].Error, "don't allow to write /shared/dbpassword")
+	cond := apimeta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeForbidden)
+	assert.NotNil(t, cond)
+	assert.Equal(t, v1alpha1.AccessDeniedReason, cond.Reason)
+	assert.False(t, apimeta.IsStatusConditionTrue(got.Status.Conditions, v1alpha1.ConditionTypeReady))
+
+	// Denied settings aren't deleted with the PushToAppConfig either.
+	assert.Nil(t, cl.Delete(context.TODO(), got))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Nil(t, store.get("/default/database/user", "prod"))
+	assert.Equal(t, "owned by someone else", store.get("/shared/dbpassword", "prod").Value)
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+
+	ssmv1alpha1 "github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/pkg/azure"
+)
+
+// Resolution is the content of the Secret of a ParameterStore resolved from
+// the settings of the App Configuration store.
+type Resolution struct {
+	// Data holds the values by their key in the Secret.
+	Data map[string]string
+	// Annotations hold the errors of the keys that still fail.
+	Annotations map[string]string
+	// Synced is the status of the keys read from the store, sorted by name.
+	Synced []ssmv1alpha1.KeyStatus
+	// Failed is the status of the keys that couldn't be read, including the
+	// keys replaced by their default or last known value.
+	Failed []ssmv1alpha1.KeyStatus
+	// Collisions describe the keys of the Secret several settings map to.
+	Collisions []string
+}
+
+// Keys returns the status of all keys sorted by name.
+func (r *Resolution) Keys() []ssmv1alpha1.KeyStatus {
+	keys := append(append([]ssmv1alpha1.KeyStatus{}, r.Synced...), r.Failed...)
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+	return keys
+}
+
+// Resolve reads the settings of the cr with appConfig and resolves the content
+// of its Secret, current holds the last known values. If keys of the
+// ParametersStoreRef still fail, their error is returned together with the
+// resolution, it depends on the failure policy whether the Secret is written.
+// Any other error is returned without resolution.
+func Resolve(appConfig *azure.AppConfigClient, cr *ssmv1alpha1.ParameterStore, current *corev1.Secret) (*Resolution, error) {
+	ref := cr.Spec.ValueFrom.ParameterStoreRef
+	var params1 []azure.Parameter
+	if ref != nil {
+		var err *azure.SSMError
+		params1, err = appConfig.ParameterStoreRefParameters(*ref)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+	var params2 []azure.Parameter
+	var anno = make(map[string]string)
+	var values map[string]string
+	var failedKeys []ssmv1alpha1.KeyStatus
+	var partial *azure.SSMError
+
+	if cr.Spec.ValueFrom.ParametersStoreRef != nil {
+		var err *azure.SSMError
+		params2, anno, err = appConfig.FetchParameters(cr.Spec.ValueFrom.ParametersStoreRef)
+
+		if err != nil {
+			if len(err.ParameterErrors) == 0 {
+				return nil, err
+			}
+			values, failedKeys, partial = resolveFailures(cr, err, current)
+			// Only the keys that still fail are annotated with their error.
+			for _, k := range failedKeys {
+				if k.State == ssmv1alpha1.KeyStateDefaulted || k.State == ssmv1alpha1.KeyStateMissing {
+					delete(anno, azure.ErrorAnnotation(k.Name))
+				}
+			}
+		}
+	}
+
+	// Values of the ParameterStoreRef take precedence over the ParametersStoreRef.
+	params := make(map[string]azure.Parameter, len(params1)+len(params2))
+	var collisions []string
+	for _, p := range append(params2, params1...) {
+		if prev, ok := params[p.Name]; ok && prev.Key != p.Key {
+			collisions = append(collisions, fmt.Sprintf("%s from %s overwritten by %s", p.Name, prev.Key, p.Key))
+		}
+		params[p.Name] = p
+	}
+	data := make(map[string]string, len(params)+len(values))
+	for k, v := range values {
+		data[k] = v
+	}
+	for k, p := range params {
+		data[k] = p.Value
+	}
+
+	res := &Resolution{
+		Data:        data,
+		Annotations: anno,
+		Synced:      keyStatus(params),
+		Failed:      failedKeys,
+		Collisions:  collisions,
+	}
+	if partial != nil {
+		return res, partial
+	}
+	return res, nil
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	ssmv1alpha1 "github.com/fr123k/az-app-config-operator/api/v1alpha1"
+)
+
+// RolloutAnnotationPrefix is the prefix of the pod template annotation that holds
+// the content hash of a Secret. Changing it triggers a rolling restart of the workload.
+const RolloutAnnotationPrefix = "rollout.aws-ssm-operator/"
+
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets,verbs=get;list;watch;patch
+
+// rolloutAnnotation returns the pod template annotation for the Secret.
+// The name part of an annotation is limited to 63 characters.
+func rolloutAnnotation(secretName string) string {
+	if len(secretName) > 63 {
+		sum := sha256.Sum256([]byte(secretName))
+		secretName = secretName[:54] + "-" + hex.EncodeToString(sum[:])[:8]
+	}
+	return RolloutAnnotationPrefix + secretName
+}
+
+// rollout stamps the content hash of the Secret into the pod template of the
+// rollout targets of the cr. If the data of the Secret didn't change only
+// workloads that already carry an outdated hash are patched, so a failed
+// rollout is retried without restarting workloads that were never rolled out.
+func (r *ParameterStoreReconciler) rollout(ctx context.Context, cr *ssmv1alpha1.ParameterStore, secretName, hash string, changed bool) error {
+	log := logf.FromContext(ctx)
+
+	workloads, err := r.rolloutWorkloads(ctx, cr, secretName)
+	if err != nil {
+		return err
+	}
+
+	key := rolloutAnnotation(secretName)
+	var errs []error
+	for _, obj := range workloads {
+		template := podTemplate(obj)
+		current, ok := template.Annotations[key]
+		if current == hash || (!ok && !changed) {
+			continue
+		}
+
+		patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+		if template.Annotations == nil {
+			template.Annotations = make(map[string]string)
+		}
+		template.Annotations[key] = hash
+
+		log.Info("Rolling out workload", "Kind", fmt.Sprintf("%T", obj), "Name", obj.GetName(), "Secret", secretName)
+		spanCtx, span := startSpan(ctx, "Workload.Patch", attribute.String("k8s.kind", fmt.Sprintf("%T", obj)), attribute.String("k8s.name", obj.GetName()))
+		err := r.Patch(spanCtx, obj, patch)
+		endSpan(span, err)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// rolloutWorkloads returns the rollout targets of the cr and, if enabled,
+// all workloads in the namespace that reference the Secret.
+func (r *ParameterStoreReconciler) rolloutWorkloads(ctx context.Context, cr *ssmv1alpha1.ParameterStore, secretName string) ([]client.Object, error) {
+	log := logf.FromContext(ctx)
+
+	seen := make(map[string]bool)
+	var workloads []client.Object
+	add := func(obj client.Object) {
+		id := fmt.Sprintf("%T/%s", obj, obj.GetName())
+		if !seen[id] {
+			seen[id] = true
+			workloads = append(workloads, obj)
+		}
+	}
+
+	for _, target := range cr.Spec.RolloutTargets {
+		obj := newWorkload(target.Kind)
+		if obj == nil {
+			log.Info("Skip rollout target with unsupported kind", "Kind", target.Kind, "Name", target.Name)
+			continue
+		}
+		err := r.Get(ctx, client.ObjectKey{Namespace: cr.Namespace, Name: target.Name}, obj)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				log.Info("Skip not existing rollout target", "Kind", target.Kind, "Name", target.Name)
+				continue
+			}
+			return nil, err
+		}
+		add(obj)
+	}
+
+	if !cr.Spec.AutoRollout {
+		return workloads, nil
+	}
+
+	deployments := &appsv1.DeploymentList{}
+	if err := r.List(ctx, deployments, client.InNamespace(cr.Namespace)); err != nil {
+		return nil, err
+	}
+	for i := range deployments.Items {
+		if referencesSecret(&deployments.Items[i].Spec.Template.Spec, secretName) {
+			add(&deployments.Items[i])
+		}
+	}
+
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := r.List(ctx, statefulSets, client.InNamespace(cr.Namespace)); err != nil {
+		return nil, err
+	}
+	for i := range statefulSets.Items {
+		if referencesSecret(&statefulSets.Items[i].Spec.Template.Spec, secretName) {
+			add(&statefulSets.Items[i])
+		}
+	}
+
+	daemonSets := &appsv1.DaemonSetList{}
+	if err := r.List(ctx, daemonSets, client.InNamespace(cr.Namespace)); err != nil {
+		return nil, err
+	}
+	for i := range daemonSets.Items {
+		if referencesSecret(&daemonSets.Items[i].Spec.Template.Spec, secretName) {
+			add(&daemonSets.Items[i])
+		}
+	}
+
+	return workloads, nil
+}
+
+func newWorkload(kind string) client.Object {
+	switch kind {
+	case "Deployment":
+		return &appsv1.Deployment{}
+	case "StatefulSet":
+		return &appsv1.StatefulSet{}
+	case "DaemonSet":
+		return &appsv1.DaemonSet{}
+	}
+	return nil
+}
+
+func podTemplate(obj client.Object) *corev1.PodTemplateSpec {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return &o.Spec.Template
+	case *appsv1.StatefulSet:
+		return &o.Spec.Template
+	case *appsv1.DaemonSet:
+		return &o.Spec.Template
+	}
+	return nil
+}
+
+// referencesSecret reports whether the pod spec uses the Secret via env, envFrom or volumes.
+func referencesSecret(spec *corev1.PodSpec, secretName string) bool {
+	for _, v := range spec.Volumes {
+		if v.Secret != nil && v.Secret.SecretName == secretName {
+			return true
+		}
+		if v.Projected != nil {
+			for _, source := range v.Projected.Sources {
+				if source.Secret != nil && source.Secret.Name == secretName {
+					return true
+				}
+			}
+		}
+	}
+
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, c := range containers {
+		for _, env := range c.EnvFrom {
+			if env.SecretRef != nil && env.SecretRef.Name == secretName {
+				return true
+			}
+		}
+		for _, env := range c.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil && env.ValueFrom.SecretKeyRef.Name == secretName {
+				return true
+			}
+		}
+	}
+	return false
+}
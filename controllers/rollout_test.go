@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/internal/testutil"
+)
+
+func deployment(name string, spec corev1.PodSpec) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{Spec: spec},
+		},
+	}
+}
+
+func TestReferencesSecret(t *testing.T) {
+	envFrom := corev1.PodSpec{Containers: []corev1.Container{{
+		EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "database"}}}},
+	}}}
+	env := corev1.PodSpec{InitContainers: []corev1.Container{{
+		Env: []corev1.EnvVar{{Name: "DB_USER", ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "database"}, Key: "DB_USER"},
+		}}},
+	}}}
+	volume := corev1.PodSpec{Volumes: []corev1.Volume{{
+		Name:         "database",
+		VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "database"}},
+	}}}
+
+	assert.True(t, referencesSecret(&envFrom, "database"))
+	assert.True(t, referencesSecret(&env, "database"))
+	assert.True(t, referencesSecret(&volume, "database"))
+	assert.False(t, referencesSecret(&volume, "other"))
+}
+
+func TestRolloutAnnotation(t *testing.T) {
+	assert.Equal(t, "rollout.aws-ssm-operator/database", rolloutAnnotation("database"))
+
+	long := rolloutAnnotation("a-very-long-secret-name-that-does-not-fit-into-the-name-of-an-annotation")
+	assert.Len(t, long, len(RolloutAnnotationPrefix)+63)
+}
+
+func TestReconcileRolloutOnChange(t *testing.T) {
+	values := map[string]string{"user": "dbuser"}
+	testutil.AppConfigServer(t, values)
+	parameterStore := testParameterStore()
+	parameterStore.Spec.ValueFrom.ParametersStoreRef = parameterStore.Spec.ValueFrom.ParametersStoreRef[:1]
+	parameterStore.Spec.AutoRollout = true
+	parameterStore.Spec.RolloutTargets = []v1alpha1.RolloutTarget{{Kind: "Deployment", Name: "target"}}
+	consumer := deployment("consumer", corev1.PodSpec{Containers: []corev1.Container{{
+		EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "database"}}}},
+	}}})
+	target := deployment("target", corev1.PodSpec{})
+	unrelated := deployment("unrelated", corev1.PodSpec{})
+
+	r, cl, req := newTestReconciler(t, parameterStore, consumer, target, unrelated)
+	key := rolloutAnnotation("database")
+
+	// Creating the Secret doesn't restart anything.
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	for _, name := range []string{"consumer", "target", "unrelated"} {
+		got := &appsv1.Deployment{}
+		assert.Nil(t, cl.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: "default"}, got))
+		assert.NotContains(t, got.Spec.Template.Annotations, key)
+	}
+
+	values["user"] = "newuser"
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+
+	hash := contentHash(map[string][]byte{"DB_USER": []byte("newuser")})
+	for _, name := range []string{"consumer", "target"} {
+		got := &appsv1.Deployment{}
+		assert.Nil(t, cl.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: "default"}, got))
+		assert.Equal(t, hash, got.Spec.Template.Annotations[key])
+	}
+	got := &appsv1.Deployment{}
+	assert.Nil(t, cl.Get(context.TODO(), types.NamespacedName{Name: "unrelated", Namespace: "default"}, got))
+	assert.NotContains(t, got.Spec.Template.Annotations, key)
+}
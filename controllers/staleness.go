@@ -0,0 +1,176 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	ssmv1alpha1 "github.com/fr123k/az-app-config-operator/api/v1alpha1"
+)
+
+// stalenessCollector reports the time since the last successful sync of every
+// ParameterStore, computed at scrape time.
+type stalenessCollector struct {
+	desc     *prometheus.Desc
+	mu       sync.Mutex
+	lastSync map[types.NamespacedName]time.Time
+}
+
+var staleness = &stalenessCollector{
+	desc: prometheus.NewDesc("parameterstore_staleness_seconds",
+		"Seconds since the last successful sync of the ParameterStore.",
+		[]string{"namespace", "name"}, nil),
+	lastSync: map[types.NamespacedName]time.Time{},
+}
+
+func init() {
+	metrics.Registry.MustRegister(staleness)
+}
+
+func (c *stalenessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *stalenessCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, t := range c.lastSync {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, time.Since(t).Seconds(), key.Namespace, key.Name)
+	}
+}
+
+// observe remembers the last successful sync of the cr.
+func (c *stalenessCollector) observe(cr *ssmv1alpha1.ParameterStore) {
+	if cr.Status.LastSyncTime == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastSync[types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}] = cr.Status.LastSyncTime.Time
+}
+
+// forget stops reporting a deleted cr.
+func (c *stalenessCollector) forget(key types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.lastSync, key)
+}
+
+// fresh marks the data of the Secret as synced.
+func fresh(instance *ssmv1alpha1.ParameterStore) {
+	apimeta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Status:             metav1.ConditionFalse,
+		Reason:             ssmv1alpha1.ReconciliationSucceededReason,
+		Message:            "Secret data is in sync",
+		Type:               ssmv1alpha1.ConditionTypeStale,
+		ObservedGeneration: instance.GetGeneration(),
+	})
+	if apimeta.FindStatusCondition(instance.Status.Conditions, ssmv1alpha1.ConditionTypeDegraded) != nil {
+		apimeta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+			Status:             metav1.ConditionFalse,
+			Reason:             ssmv1alpha1.ReconciliationSucceededReason,
+			Message:            "Secret data is in sync",
+			Type:               ssmv1alpha1.ConditionTypeDegraded,
+			ObservedGeneration: instance.GetGeneration(),
+		})
+	}
+}
+
+// stale records how long the data of the Secret wasn't synced and degrades the
+// cr once that exceeds its max staleness. Degraded Secrets are removed if the
+// cr asks for it, so consumers fail closed instead of using stale credentials.
+func (r *ParameterStoreReconciler) stale(ctx context.Context, instance *ssmv1alpha1.ParameterStore) error {
+	if instance.Status.LastSyncTime == nil {
+		return nil
+	}
+	age := time.Since(instance.Status.LastSyncTime.Time).Truncate(time.Second)
+	apimeta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Status:             metav1.ConditionTrue,
+		Reason:             ssmv1alpha1.SyncFailedReason,
+		Message:            fmt.Sprintf("Secret data was last synced %s ago at %s", age, instance.Status.LastSyncTime.Format(time.RFC3339)),
+		Type:               ssmv1alpha1.ConditionTypeStale,
+		ObservedGeneration: instance.GetGeneration(),
+	})
+
+	max := instance.Spec.MaxStaleness
+	if max == nil || age <= max.Duration {
+		return nil
+	}
+	message := fmt.Sprintf("Secret data is stale for %s, longer than the max staleness of %s", age, max.Duration)
+	if !apimeta.IsStatusConditionTrue(instance.Status.Conditions, ssmv1alpha1.ConditionTypeDegraded) && r.Recorder != nil {
+		r.Recorder.Eventf(instance, nil, corev1.EventTypeWarning, ssmv1alpha1.MaxStalenessExceededReason, "Sync", "%s", message)
+	}
+	apimeta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Status:             metav1.ConditionTrue,
+		Reason:             ssmv1alpha1.MaxStalenessExceededReason,
+		Message:            message,
+		Type:               ssmv1alpha1.ConditionTypeDegraded,
+		ObservedGeneration: instance.GetGeneration(),
+	})
+
+	if !instance.Spec.DeleteStaleSecret {
+		return nil
+	}
+	return r.removeStaleSecret(ctx, instance)
+}
+
+// removeStaleSecret deletes the Secret created by the operator, of a shared
+// Secret only the keys applied by the operator are removed.
+func (r *ParameterStoreReconciler) removeStaleSecret(ctx context.Context, instance *ssmv1alpha1.ParameterStore) error {
+	log := logf.FromContext(ctx)
+
+	current := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, current)
+	if errors.IsNotFound(err) {
+		instance.Status.SecretStatus = nil
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Info("Removing stale Secret", "Secret.Name", current.Name)
+	if metav1.IsControlledBy(current, instance) {
+		spanCtx, span := startSpan(ctx, "Secret.Delete", attribute.String("k8s.name", current.Name))
+		err = r.Delete(spanCtx, current)
+		endSpan(span, err)
+	} else {
+		// Applying no fields releases all fields owned by the operator.
+		err = r.applySecret(ctx, corev1ac.Secret(current.Name, current.Namespace), client.FieldOwner(FieldManager), client.ForceOwnership)
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	// The Secret was removed on purpose, it isn't restored as drifted.
+	instance.Status.SecretStatus = nil
+	return nil
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+)
+
+func TestReconcileStaleSecret(t *testing.T) {
+	var down atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Sync-Token", "id=value;sn=0")
+		if down.Load() {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = rw.Write([]byte(`{"etag": "etag", "key": "user", "value": "dbuser"}`))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("LOCAL_STACK_ENDPOINT", server.URL)
+	parameterStore := testParameterStore()
+	parameterStore.Name = "stale"
+	parameterStore.Spec.ValueFrom.ParametersStoreRef = parameterStore.Spec.ValueFrom.ParametersStoreRef[:1]
+	parameterStore.Spec.MaxStaleness = &metav1.Duration{Duration: time.Hour}
+	parameterStore.Spec.DeleteStaleSecret = true
+	r, cl, req := newTestReconciler(t, parameterStore)
+	r.AppConfig.Client = newAppConfigClientWithoutRetries(t, server.URL)
+	recorder := events.NewFakeRecorder(10)
+	r.Recorder = recorder
+
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, "Normal SecretCreated Secret stale created with 1 keys", <-recorder.Events)
+	got := &v1alpha1.ParameterStore{}
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.True(t, apimeta.IsStatusConditionFalse(got.Status.Conditions, v1alpha1.ConditionTypeStale))
+
+	// The store is down, the Secret keeps the data of the last sync.
+	down.Store(true)
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NotNil(t, err)
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.True(t, apimeta.IsStatusConditionTrue(got.Status.Conditions, v1alpha1.ConditionTypeStale))
+	assert.Nil(t, apimeta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeDegraded))
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, &corev1.Secret{}))
+
+	// Longer than the max staleness the Secret is removed.
+	got.Status.LastSyncTime = &metav1.Time{Time: time.Now().Truncate(time.Second).Add(-2 * time.Hour)}
+	assert.Nil(t, cl.Status().Update(context.TODO(), got))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NotNil(t, err)
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	degraded := apimeta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeDegraded)
+	if assert.NotNil(t, degraded) {
+		assert.Equal(t, metav1.ConditionTrue, degraded.Status)
+		assert.Equal(t, v1alpha1.MaxStalenessExceededReason, degraded.Reason)
+	}
+	assert.Contains(t, apimeta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeStale).Message, "2h0m0s ago")
+	assert.Nil(t, got.Status.SecretStatus)
+	assert.True(t, apierrors.IsNotFound(cl.Get(context.TODO(), req.NamespacedName, &corev1.Secret{})))
+	assert.Equal(t, "Warning MaxStalenessExceeded Secret data is stale for 2h0m0s, longer than the max staleness of 1h0m0s", <-recorder.Events)
+
+	assert.GreaterOrEqual(t, testutil.CollectAndCount(staleness, "parameterstore_staleness_seconds"), 1)
+	staleness.mu.Lock()
+	assert.InDelta(t, 2*time.Hour, time.Since(staleness.lastSync[req.NamespacedName]), float64(time.Minute))
+	staleness.mu.Unlock()
+
+	// Once the store is back the Secret is synced again.
+	down.Store(false)
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, got))
+	assert.True(t, apimeta.IsStatusConditionFalse(got.Status.Conditions, v1alpha1.ConditionTypeStale))
+	assert.True(t, apimeta.IsStatusConditionFalse(got.Status.Conditions, v1alpha1.ConditionTypeDegraded))
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, &corev1.Secret{}))
+	assert.Equal(t, "Normal SecretCreated Secret stale created with 1 keys", <-recorder.Events)
+	assert.Empty(t, recorder.Events)
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	ssmv1alpha1 "github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/pkg/azure"
+)
+
+// storeClients holds the clients of the App Configuration stores other than
+// the store of the operator, they are created on first use.
+type storeClients struct {
+	mu      sync.Mutex
+	clients map[string]*azure.AppConfigClient
+}
+
+// appConfig returns the client reading the settings of the cr from its store
+// with its label and key mapping.
+func (r *ParameterStoreReconciler) appConfig(ctx context.Context, cr *ssmv1alpha1.ParameterStore) (*azure.AppConfigClient, error) {
+	cli := r.AppConfig
+	if store := cr.Spec.Store; store != "" {
+		var err error
+		if cli, err = r.storeClient(store); err != nil {
+			return nil, err
+		}
+	}
+	return cli.WithContext(ctx).WithLabel(cr.Spec.Label).WithKeyMapping(cr.Spec.KeyMapping), nil
+}
+
+func (r *ParameterStoreReconciler) storeClient(store string) (*azure.AppConfigClient, error) {
+	if r.NewAppConfig == nil || !slices.Contains(r.Stores, store) {
+		return nil, fmt.Errorf("App Configuration store %s is not supported, only the store of the operator and %v are", store, r.Stores)
+	}
+
+	r.stores.mu.Lock()
+	defer r.stores.mu.Unlock()
+	if cli, ok := r.stores.clients[store]; ok {
+		return cli, nil
+	}
+	cli, err := r.NewAppConfig(store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the client of App Configuration store %s: %w", store, err)
+	}
+	if r.stores.clients == nil {
+		r.stores.clients = make(map[string]*azure.AppConfigClient)
+	}
+	r.stores.clients[store] = cli
+	return cli, nil
+}
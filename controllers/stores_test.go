@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/pkg/azure"
+)
+
+func storeReconciler(t *testing.T, spec v1alpha1.ParameterStoreSpec) (*ParameterStoreReconciler, ctrl.Request) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		t.Errorf("unexpected request of the operator store: %s", req.URL)
+		rw.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("LOCAL_STACK_ENDPOINT", server.URL)
+	parameterStore := testParameterStore()
+	parameterStore.Name = "store"
+	parameterStore.Spec = spec
+	r, _, req := newTestReconciler(t, parameterStore)
+	return r, req
+}
+
+func TestReconcileStoreLabelAndKeyMapping(t *testing.T) {
+	team := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Sync-Token", "id=value;sn=0")
+		assert.Equal(t, "/kv/app/db-user", req.URL.Path)
+		assert.Equal(t, "prod", req.URL.Query().Get("label"))
+		_, _ = rw.Write([]byte(`{"etag": "etag", "key": "app/db-user", "label": "prod", "value": "dbuser"}`))
+	}))
+	t.Cleanup(team.Close)
+
+	r, req := storeReconciler(t, v1alpha1.ParameterStoreSpec{
+		ValueFrom:       v1alpha1.ValueFrom{ParametersStoreRef: []v1alpha1.ParametersStoreRef{{Key: "app/db-user"}}},
+		Store:           "team-store",
+		Label:           "prod",
+		KeyMapping:      v1alpha1.KeyMappingLastSegment,
+		RefreshInterval: &metav1.Duration{Duration: 5 * time.Minute},
+	})
+	r.Stores = []string{"team-store"}
+	var created []string
+	r.NewAppConfig = func(store string) (*azure.AppConfigClient, error) {
+		created = append(created, store)
+		return &azure.AppConfigClient{Client: newAppConfigClientWithoutRetries(t, team.URL)}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		result, err := r.Reconcile(context.TODO(), req)
+		assert.Nil(t, err)
+		// The values are synced again after the refresh interval.
+		assert.Equal(t, 5*time.Minute, result.RequeueAfter)
+	}
+	assert.Equal(t, []string{"team-store"}, created)
+
+	secret := &corev1.Secret{}
+	assert.Nil(t, r.Get(context.TODO(), req.NamespacedName, secret))
+	assert.Equal(t, map[string][]byte{"db-user": []byte("dbuser")}, secret.Data)
+}
+
+func TestReconcileUnsupportedStore(t *testing.T) {
+	r, req := storeReconciler(t, v1alpha1.ParameterStoreSpec{
+		ValueFrom: v1alpha1.ValueFrom{ParametersStoreRef: []v1alpha1.ParametersStoreRef{{Key: "user"}}},
+		Store:     "team-store",
+	})
+
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.NotNil(t, err)
+
+	ps := &v1alpha1.ParameterStore{}
+	assert.Nil(t, r.Get(context.TODO(), req.NamespacedName, ps))
+	assert.Contains(t, ps.Status.SSMStatus.Error, "store team-store is not supported")
+	assert.True(t, apimeta.IsStatusConditionFalse(ps.Status.Conditions, v1alpha1.ConditionTypeSSMError))
+
+	// Stores the operator isn't configured for aren't read either.
+	r.Stores = []string{"other-store"}
+	r.NewAppConfig = func(store string) (*azure.AppConfigClient, error) {
+		t.Errorf("unexpected client of store %s", store)
+		return nil, nil
+	}
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NotNil(t, err)
+	assert.Nil(t, r.Get(context.TODO(), req.NamespacedName, ps))
+	assert.Contains(t, ps.Status.SSMStatus.Error, "store team-store is not supported, only the store of the operator and [other-store] are")
+}
@@ -30,7 +30,7 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
-	ssmv1alpha1 "github.com/fr123k/aws-ssm-operator/api/v1alpha1"
+	ssmv1alpha1 "github.com/fr123k/az-app-config-operator/api/v1alpha1"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/pkg/azure"
+)
+
+// inMemoryTracing records the spans of the test with the global tracer provider.
+func inMemoryTracing(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return exporter
+}
+
+func TestReconcileSpans(t *testing.T) {
+	exporter := inMemoryTracing(t)
+	r, _, req := eventsReconciler(t, func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte(`{"key": "user", "value": "dbuser"}`))
+	}, v1alpha1.ParametersStoreRef{Name: "DB_USER", Key: "user"})
+	// The client with the tracing provider of the operator.
+	appConfig, err := azure.NewAppClient(nil)
+	assert.Nil(t, err)
+	r.AppConfig = appConfig
+
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	reconcile, ok := spans["ParameterStore.Reconcile"]
+	if !assert.True(t, ok) {
+		return
+	}
+	for _, name := range []string{"AppConfigClient.Get", "Secret.Apply", "ParameterStore.PatchStatus"} {
+		if assert.Contains(t, spans, name) {
+			assert.Equal(t, reconcile.SpanContext.SpanID(), spans[name].Parent.SpanID(), name)
+		}
+	}
+	// The spans of the azcore pipeline are children of the client span.
+	var sdkSpans []string
+	for _, span := range exporter.GetSpans() {
+		if span.Parent.SpanID() == spans["AppConfigClient.Get"].SpanContext.SpanID() {
+			sdkSpans = append(sdkSpans, span.Name)
+		}
+	}
+	assert.NotEmpty(t, sdkSpans)
+}
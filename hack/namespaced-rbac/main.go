@@ -0,0 +1,166 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command namespaced-rbac derives the Roles of an operator restricted to a set
+// of namespaces with --watch-namespaces from the ClusterRole generated by
+// controller-gen, so both are built from the same RBAC markers.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// clusterScoped are the cluster-scoped resources of the generated ClusterRole,
+// their rules stay in a ClusterRole.
+var clusterScoped = map[string]bool{
+	"namespaces":                true,
+	"appconfigaccesspolicies":   true,
+	"customresourcedefinitions": true,
+}
+
+// migrationOnly are the resources only the storage version migrator of an
+// operator watching all namespaces needs, a restricted one leaves the stored
+// versions of the CRD as is.
+var migrationOnly = map[string]bool{
+	"customresourcedefinitions/status": true,
+}
+
+func main() {
+	role := flag.String("role", "config/rbac/role.yaml", "The ClusterRole generated by controller-gen.")
+	namespaces := flag.String("namespaces", "", "Comma-separated list of the namespaces the operator watches.")
+	prefix := flag.String("name-prefix", "aws-ssm-", "The prefix of the names of the Roles and the service account, like the namePrefix of the kustomization.")
+	saNamespace := flag.String("service-account-namespace", "aws-ssm", "The namespace of the service account of the operator.")
+	flag.Parse()
+
+	if err := run(os.Stdout, *role, *namespaces, *prefix, *saNamespace); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(w io.Writer, role, namespaces, prefix, saNamespace string) error {
+	var watched []string
+	for _, ns := range strings.Split(namespaces, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			watched = append(watched, ns)
+		}
+	}
+	if len(watched) == 0 {
+		return errors.New("-namespaces is required")
+	}
+
+	data, err := os.ReadFile(role)
+	if err != nil {
+		return err
+	}
+	clusterRole := &rbacv1.ClusterRole{}
+	if err := yaml.UnmarshalStrict(data, clusterRole); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", role, err)
+	}
+	namespaced, cluster := splitRules(clusterRole.Rules)
+
+	name := prefix + clusterRole.Name
+	subjects := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: prefix + "controller-manager", Namespace: saNamespace}}
+	objects := []runtime.Object{
+		&rbacv1.ClusterRole{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Rules:      cluster,
+		},
+		&rbacv1.ClusterRoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+			ObjectMeta: metav1.ObjectMeta{Name: name + "binding"},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: name},
+			Subjects:   subjects,
+		},
+	}
+	for _, ns := range watched {
+		objects = append(objects,
+			&rbacv1.Role{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "Role"},
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+				Rules:      namespaced,
+			},
+			&rbacv1.RoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "RoleBinding"},
+				ObjectMeta: metav1.ObjectMeta{Name: name + "binding", Namespace: ns},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: name},
+				Subjects:   subjects,
+			})
+	}
+
+	for i, obj := range objects {
+		if i > 0 {
+			fmt.Fprintln(w, "---")
+		}
+		if err := printObject(w, obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitRules splits the rules into the rules of namespaced and of cluster-scoped resources.
+func splitRules(rules []rbacv1.PolicyRule) (namespaced, cluster []rbacv1.PolicyRule) {
+	for _, rule := range rules {
+		var ns, cl []string
+		for _, r := range rule.Resources {
+			switch {
+			case migrationOnly[r]:
+			case clusterScoped[r]:
+				cl = append(cl, r)
+			default:
+				ns = append(ns, r)
+			}
+		}
+		if len(ns) > 0 {
+			r := *rule.DeepCopy()
+			r.Resources = ns
+			namespaced = append(namespaced, r)
+		}
+		if len(cl) > 0 {
+			r := *rule.DeepCopy()
+			r.Resources = cl
+			cluster = append(cluster, r)
+		}
+	}
+	return namespaced, cluster
+}
+
+// printObject writes the object as YAML without the empty creation timestamp.
+func printObject(w io.Writer, obj runtime.Object) error {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return err
+	}
+	delete(u["metadata"].(map[string]interface{}), "creationTimestamp")
+	out, err := yaml.Marshal(u)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
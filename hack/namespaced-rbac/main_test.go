@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestRun(t *testing.T) {
+	var out bytes.Buffer
+	assert.Nil(t, run(&out, "../../config/rbac/role.yaml", "team-a, team-b", "tenant-", "operators"))
+
+	docs := strings.Split(out.String(), "---\n")
+	assert.Len(t, docs, 6)
+
+	clusterRole := &rbacv1.ClusterRole{}
+	assert.Nil(t, yaml.UnmarshalStrict([]byte(docs[0]), clusterRole))
+	assert.Equal(t, "tenant-manager-role", clusterRole.Name)
+	var clusterResources []string
+	for _, rule := range clusterRole.Rules {
+		clusterResources = append(clusterResources, rule.Resources...)
+	}
+	assert.NotContains(t, clusterResources, "secrets")
+	assert.Contains(t, clusterResources, "customresourcedefinitions")
+	assert.NotContains(t, clusterResources, "customresourcedefinitions/status")
+
+	for i, ns := range []string{"team-a", "team-b"} {
+		role := &rbacv1.Role{}
+		assert.Nil(t, yaml.UnmarshalStrict([]byte(docs[2+2*i]), role))
+		assert.Equal(t, ns, role.Namespace)
+		var resources []string
+		for _, rule := range role.Rules {
+			resources = append(resources, rule.Resources...)
+		}
+		assert.Contains(t, resources, "secrets")
+		assert.Contains(t, resources, "parameterstores")
+		assert.NotContains(t, resources, "namespaces")
+		assert.NotContains(t, resources, "appconfigaccesspolicies")
+
+		binding := &rbacv1.RoleBinding{}
+		assert.Nil(t, yaml.UnmarshalStrict([]byte(docs[3+2*i]), binding))
+		assert.Equal(t, ns, binding.Namespace)
+		assert.Equal(t, rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "tenant-manager-role"}, binding.RoleRef)
+		assert.Equal(t, []rbacv1.Subject{{Kind: "ServiceAccount", Name: "tenant-controller-manager", Namespace: "operators"}}, binding.Subjects)
+	}
+}
+
+func TestRunWithoutNamespaces(t *testing.T) {
+	assert.EqualError(t, run(&bytes.Buffer{}, "../../config/rbac/role.yaml", " ", "", "aws-ssm"), "-namespaces is required")
+}
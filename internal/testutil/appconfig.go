@@ -0,0 +1,64 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package testutil holds the helpers shared by the tests of the operator and the CLI.
+package testutil
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// AppConfigServer serves the settings by their key until the test ends, lists
+// return the settings matching one of the keys of the comma-separated filter.
+// The App Configuration clients created by the test use it as LOCAL_STACK_ENDPOINT.
+func AppConfigServer(t *testing.T, values map[string]string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Sync-Token", "id=value;sn=0")
+		switch req.URL.Path {
+		case "/kv", "/revisions":
+			filter := req.URL.Query().Get("key")
+			var items []string
+			for key, value := range values {
+				for _, f := range strings.Split(filter, ",") {
+					if key == f || (strings.HasSuffix(f, "*") && strings.HasPrefix(key, strings.TrimSuffix(f, "*"))) {
+						items = append(items, setting(key, value))
+					}
+				}
+			}
+			_, _ = fmt.Fprintf(rw, `{"items": [%s]}`, strings.Join(items, ","))
+		default:
+			key := strings.TrimPrefix(req.URL.Path, "/kv/")
+			value, ok := values[key]
+			if !ok {
+				rw.WriteHeader(http.StatusNotFound)
+				_, _ = rw.Write([]byte(`{"__type":"Parameter not found", "message": "The parameter was not found"}`))
+				return
+			}
+			_, _ = rw.Write([]byte(setting(key, value)))
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("LOCAL_STACK_ENDPOINT", server.URL)
+}
+
+func setting(key, value string) string {
+	return fmt.Sprintf(`{"etag": "4f6dd610dd5e4deebc7fbaef685fb903", "key": %q, "label": "", "value": %q, "last_modified": "2017-12-05T02:41:26+00:00", "locked": false}`, key, value)
+}
@@ -17,28 +17,44 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
-	ssmv1alpha1 "github.com/fr123k/aws-ssm-operator/api/v1alpha1"
-	"github.com/fr123k/aws-ssm-operator/controllers"
+	ssmv1alpha1 "github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	ssmv1beta1 "github.com/fr123k/az-app-config-operator/api/v1beta1"
+	"github.com/fr123k/az-app-config-operator/controllers"
 
 	//+kubebuilder:scaffold:imports
 
-	awsCli "github.com/fr123k/aws-ssm-operator/pkg/aws"
+	"github.com/fr123k/az-app-config-operator/pkg/azure"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 var (
@@ -49,7 +65,10 @@ var (
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
+
 	utilruntime.Must(ssmv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(ssmv1beta1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -57,8 +76,32 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var appConfigName string
+	var appConfigQPS float64
+	var appConfigBurst int
+	var appConfigCacheTTL time.Duration
+	var appConfigBatchSize int
+	var appConfigConcurrency int
+	var appConfigStores string
+	var keyVaultDNSSuffixes string
+	var otlpEndpoint string
+	var traceSamplingRatio float64
+	var watchNamespaces string
+	var watchLabelSelector string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.StringVar(&appConfigName, "app-config-name", os.Getenv("APP_CONFIG_NAME"), "The name of the Azure App Configuration store to read values from.")
+	flag.Float64Var(&appConfigQPS, "app-config-qps", 10, "The maximum number of requests per second sent to the App Configuration store, 0 disables the limit.")
+	flag.IntVar(&appConfigBurst, "app-config-burst", 20, "The maximum burst of requests sent to the App Configuration store.")
+	flag.IntVar(&appConfigBatchSize, "app-config-batch-size", 5, "The number of keys fetched from the App Configuration store with one request, at most 5.")
+	flag.IntVar(&appConfigConcurrency, "app-config-concurrency", 4, "The number of requests a ParameterStore sends to the App Configuration store in parallel.")
+	flag.StringVar(&appConfigStores, "app-config-stores", os.Getenv("APP_CONFIG_STORES"), "Comma-separated list of the other App Configuration stores ParameterStores may read from with spec.store, only the store of the operator if empty.")
+	flag.StringVar(&keyVaultDNSSuffixes, "key-vault-dns-suffixes", os.Getenv("KEY_VAULT_DNS_SUFFIXES"), "Comma-separated list of the DNS suffixes of the Key Vaults PushToAppConfigs may write to and Key Vault references are resolved from, e.g. vault.azure.cn for Azure China, vault.azure.net if empty.")
+	flag.DurationVar(&appConfigCacheTTL, "app-config-cache-ttl", 30*time.Second, "How long values fetched from the App Configuration store are shared between ParameterStores before they are revalidated, 0 disables the cache.")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"), "The URL of the OTLP/HTTP endpoint traces are exported to, e.g. http://otel-collector:4318/v1/traces. Tracing is disabled if empty.")
+	flag.Float64Var(&traceSamplingRatio, "trace-sampling-ratio", 1, "The ratio of reconciles that are traced, between 0 and 1.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", os.Getenv("WATCH_NAMESPACES"), "Comma-separated list of the namespaces the operator watches, all namespaces if empty.")
+	flag.StringVar(&watchLabelSelector, "watch-label-selector", "", "Label selector of the ParameterStores and PushToAppConfigs the operator syncs, all of them if empty.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
@@ -70,8 +113,30 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if otlpEndpoint != "" {
+		tp, err := newTracerProvider(otlpEndpoint, traceSamplingRatio)
+		if err != nil {
+			setupLog.Error(err, "unable to create OTLP trace exporter")
+			os.Exit(1)
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = tp.Shutdown(ctx)
+		}()
+		otel.SetTracerProvider(tp)
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	}
+
+	cacheOpts, err := cacheOptions(watchNamespaces, watchLabelSelector)
+	if err != nil {
+		setupLog.Error(err, "unable to configure the cache")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
+		Cache:                  cacheOpts,
 		Metrics:                metricsserver.Options{BindAddress: metricsAddr},
 		WebhookServer:          webhook.NewServer(webhook.Options{Port: 9443}),
 		HealthProbeBindAddress: probeAddr,
@@ -83,14 +148,87 @@ func main() {
 		os.Exit(1)
 	}
 
+	appConfig, err := azure.NewAppClient(&appConfigName)
+	if err != nil {
+		setupLog.Error(err, "unable to create app configuration client")
+		os.Exit(1)
+	}
+	appConfig.SetRateLimit(appConfigQPS, appConfigBurst)
+	appConfig.SetCacheTTL(appConfigCacheTTL)
+	appConfig.SetBatching(appConfigBatchSize, appConfigConcurrency)
+
+	keyVaults, err := azure.NewKeyVaultResolver(parseList(keyVaultDNSSuffixes))
+	if err != nil {
+		setupLog.Error(err, "unable to create Key Vault resolver")
+		os.Exit(1)
+	}
+	appConfig.SetKeyVaultResolver(keyVaults)
+
+	stores, err := parseStores(appConfigStores)
+	if err != nil {
+		setupLog.Error(err, "unable to parse the App Configuration stores")
+		os.Exit(1)
+	}
+	// The clients of the stores set by ParameterStores are configured like the
+	// client of the operator store.
+	newAppConfig := func(store string) (*azure.AppConfigClient, error) {
+		cli, err := azure.NewAppClient(&store)
+		if err != nil {
+			return nil, err
+		}
+		cli.SetRateLimit(appConfigQPS, appConfigBurst)
+		cli.SetCacheTTL(appConfigCacheTTL)
+		cli.SetBatching(appConfigBatchSize, appConfigConcurrency)
+		cli.SetKeyVaultResolver(keyVaults)
+		return cli, nil
+	}
+
 	if err = (&controllers.ParameterStoreReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-		SSMc:   awsCli.NewSSMClient(nil),
+		Client:       mgr.GetClient(),
+		Scheme:       mgr.GetScheme(),
+		AppConfig:    appConfig,
+		Recorder:     mgr.GetEventRecorder("parameterstore-controller"),
+		NewAppConfig: newAppConfig,
+		Stores:       stores,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ParameterStore")
 		os.Exit(1)
 	}
+	if err = (&controllers.PushToAppConfigReconciler{
+		Client:              mgr.GetClient(),
+		Scheme:              mgr.GetScheme(),
+		AppConfig:           appConfig,
+		KeyVaultDNSSuffixes: parseList(keyVaultDNSSuffixes),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PushToAppConfig")
+		os.Exit(1)
+	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&ssmv1alpha1.ParameterStore{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ParameterStore")
+			os.Exit(1)
+		}
+		if err = (&ssmv1beta1.ParameterStore{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ParameterStore", "version", "v1beta1")
+			os.Exit(1)
+		}
+		if err = (&ssmv1alpha1.PushToAppConfig{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PushToAppConfig")
+			os.Exit(1)
+		}
+	}
+	// ParameterStores stored in v1alpha1 are rewritten in the storage version,
+	// which needs the conversion webhook to be served. The ParameterStores are
+	// read without the cache, so the label selector doesn't apply to them.
+	if err = mgr.Add(&controllers.StorageVersionMigrator{
+		Client:     mgr.GetClient(),
+		Reader:     mgr.GetAPIReader(),
+		Namespaces: parseList(watchNamespaces),
+		Interval:   time.Minute,
+	}); err != nil {
+		setupLog.Error(err, "unable to add storage version migrator")
+		os.Exit(1)
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -108,3 +246,73 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// cacheOptions restricts the cache of the manager to the comma-separated
+// namespaces, and the ParameterStores and PushToAppConfigs to the label selector.
+// The operator only reconciles the objects in its cache.
+func cacheOptions(namespaces, selector string) (cache.Options, error) {
+	var opts cache.Options
+	for _, ns := range strings.Split(namespaces, ",") {
+		if ns = strings.TrimSpace(ns); ns == "" {
+			continue
+		}
+		if opts.DefaultNamespaces == nil {
+			opts.DefaultNamespaces = map[string]cache.Config{}
+		}
+		opts.DefaultNamespaces[ns] = cache.Config{}
+	}
+	if selector != "" {
+		sel, err := labels.Parse(selector)
+		if err != nil {
+			return opts, fmt.Errorf("invalid label selector %q: %w", selector, err)
+		}
+		opts.ByObject = map[client.Object]cache.ByObject{
+			&ssmv1alpha1.ParameterStore{}:  {Label: sel},
+			&ssmv1alpha1.PushToAppConfig{}: {Label: sel},
+		}
+	}
+	return opts, nil
+}
+
+// parseStores returns the names of the comma-separated stores ParameterStores may read from.
+func parseStores(list string) ([]string, error) {
+	var stores []string
+	for _, store := range strings.Split(list, ",") {
+		if store = strings.TrimSpace(store); store == "" {
+			continue
+		}
+		if !ssmv1alpha1.ValidStoreName(store) {
+			return nil, fmt.Errorf("invalid App Configuration store name %q", store)
+		}
+		stores = append(stores, store)
+	}
+	return stores, nil
+}
+
+// parseList returns the non-empty items of the comma-separated list.
+func parseList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// newTracerProvider exports the sampled spans in batches to the OTLP/HTTP endpoint.
+func newTracerProvider(endpoint string, ratio float64) (*sdktrace.TracerProvider, error) {
+	exporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", "az-app-config-operator")))
+	if err != nil {
+		return nil, err
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	), nil
+}
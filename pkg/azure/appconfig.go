@@ -4,41 +4,76 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig"
-	"github.com/fr123k/aws-ssm-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
 	errs "github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type AppConfigClient struct {
 	Client *azappconfig.Client
 	ctx    context.Context
+
+	throttle    *throttlePolicy
+	cache       *cache
+	batchSize   int
+	concurrency int
+	label       string
+	keyMapping  string
+	keyVaults   *KeyVaultResolver
 }
 
-func endpoint(name *string) string {
+// endpoint returns the URL of the store. The name is checked before it becomes
+// the host the requests and the credentials of the operator are sent to.
+func endpoint(name *string) (string, error) {
 	if lsEp := os.Getenv("LOCAL_STACK_ENDPOINT"); lsEp != "" {
-		return lsEp
+		return lsEp, nil
+	}
+	if name == nil {
+		return "", errs.New("no App Configuration store name")
+	}
+	if !v1alpha1.ValidStoreName(*name) {
+		return "", fmt.Errorf("invalid App Configuration store name %q", *name)
 	}
-	return fmt.Sprintf("https://%s.azconfig.io", *name)
+	return fmt.Sprintf("https://%s.azconfig.io", *name), nil
 }
 
 func NewAppClient(name *string) (*AppConfigClient, error) {
+	ep, err := endpoint(name)
+	if err != nil {
+		return nil, err
+	}
+	store := strings.TrimPrefix(strings.TrimPrefix(ep, "https://"), "http://")
+	throttle := newThrottlePolicy(store)
+	options := &azappconfig.ClientOptions{
+		ClientOptions: policy.ClientOptions{
+			PerCallPolicies:  []policy.Policy{newAppConfigMetricsPolicy(store)},
+			PerRetryPolicies: []policy.Policy{throttle},
+			TracingProvider:  tracingProvider(),
+		},
+	}
+
 	if lsEp := os.Getenv("LOCAL_STACK_ENDPOINT"); lsEp != "" {
 		// For local testing the endpoint is an HTTP test server. NewClient uses
 		// a bearer-token policy that rejects authenticated requests over HTTP
 		// (azcore >= v1.18), so use NewClientFromConnectionString (HMAC auth)
 		// which has no such restriction.
 		connStr := fmt.Sprintf("Endpoint=%s;Id=test;Secret=%s", lsEp, base64.StdEncoding.EncodeToString([]byte("test")))
-		client, err := azappconfig.NewClientFromConnectionString(connStr, nil)
+		client, err := azappconfig.NewClientFromConnectionString(connStr, options)
 		if err != nil {
 			return nil, err
 		}
 		ctx := context.TODO()
-		return &AppConfigClient{Client: client, ctx: ctx}, err
+		return &AppConfigClient{Client: client, ctx: ctx, throttle: throttle}, err
 	}
 	credential, err := azidentity.NewDefaultAzureCredential(nil)
 
@@ -46,102 +81,314 @@ func NewAppClient(name *string) (*AppConfigClient, error) {
 		return nil, err
 	}
 
-	client, err := azappconfig.NewClient(endpoint(name), credential, nil)
+	client, err := azappconfig.NewClient(ep, credential, options)
 	if err != nil {
 		return nil, err
 	}
 	ctx := context.TODO()
-	return &AppConfigClient{Client: client, ctx: ctx}, err
+	return &AppConfigClient{Client: client, ctx: ctx, throttle: throttle}, err
+}
+
+// Parameter is a value fetched from App Configuration together with the
+// metadata of the setting it was read from.
+type Parameter struct {
+	// Name is the key of the value in the Secret.
+	Name         string
+	Key          string
+	Label        string
+	Value        string
+	ETag         string
+	ContentType  string
+	LastModified *time.Time
+	Locked       bool
+}
+
+func newParameter(name string, setting azappconfig.Setting) Parameter {
+	p := Parameter{Name: name, Key: *setting.Key, LastModified: setting.LastModified}
+	if setting.Value != nil {
+		p.Value = *setting.Value
+	}
+	if setting.Label != nil {
+		p.Label = *setting.Label
+	}
+	if setting.ETag != nil {
+		p.ETag = string(*setting.ETag)
+	}
+	if setting.ContentType != nil {
+		p.ContentType = *setting.ContentType
+	}
+	if setting.IsReadOnly != nil {
+		p.Locked = *setting.IsReadOnly
+	}
+	return p
+}
+
+// SecretKey derives the key of the Secret from the last segment of the setting key.
+func SecretKey(key string) string {
+	return v1alpha1.SecretKey(key)
+}
+
+// WithLabel returns a client reading the settings with the label, if empty keys
+// are read without label and paths with any label. The client shares the cache
+// and the rate limit with cli.
+func (cli *AppConfigClient) WithLabel(label string) *AppConfigClient {
+	c := *cli
+	c.label = label
+	return &c
+}
+
+// WithKeyMapping returns a client naming the parameters without name by the key
+// mapping strategy, see v1alpha1.MapKey.
+func (cli *AppConfigClient) WithKeyMapping(keyMapping string) *AppConfigClient {
+	c := *cli
+	c.keyMapping = keyMapping
+	return &c
+}
+
+// labelPtr is the label of single settings, nil reads the setting without label.
+func (cli *AppConfigClient) labelPtr() *string {
+	if cli.label == "" {
+		return nil
+	}
+	return to.Ptr(cli.label)
+}
+
+// Values returns the values of the parameters by their name.
+func Values(params []Parameter) map[string]string {
+	m := make(map[string]string, len(params))
+	for _, p := range params {
+		m[p.Name] = p.Value
+	}
+	return m
 }
 
 // SSMParameterValueToSecret shapes fetched value so as to store them into K8S Secret
 func (cli *AppConfigClient) SSMParameterValueToSecret(ref v1alpha1.ParameterStoreRef) (map[string]string, *SSMError) {
+	params, err := cli.ParameterStoreRefParameters(ref)
+	if err != nil {
+		return nil, err
+	}
+	return Values(params), nil
+}
+
+// ParameterStoreRefParameters fetches the settings selected by the ref with
+// their metadata, Key Vault references are resolved.
+func (cli *AppConfigClient) ParameterStoreRefParameters(ref v1alpha1.ParameterStoreRef) ([]Parameter, *SSMError) {
+	var params []Parameter
 	if ref.Name != "" {
-		return cli.Get(ref.Name)
+		p, err := cli.GetParameter(ref.Name)
+		if err != nil {
+			return nil, err
+		}
+		params = []Parameter{*p}
 	} else if ref.Path != "" {
-		return cli.List(fmt.Sprintf("%s*", ref.Path))
+		var err *SSMError
+		params, err = cli.ListParameters(fmt.Sprintf("%s*", ref.Path))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, newClassifiedError(ErrInvalidRef, "Invalid ParameterStoreRef provided atleast Name or Path has to be set.")
 	}
-	return nil, NewSSMError("Invalid ParameterStoreRef provided atleast Name or Path has to be set.")
+	if err := cli.resolveReferences(params); err != nil {
+		return nil, err
+	}
+	return params, nil
 }
 
 func (cli *AppConfigClient) Get(key string) (map[string]string, *SSMError) {
+	p, err := cli.GetParameter(key)
+	if err != nil {
+		return nil, err
+	}
+	return Values([]Parameter{*p}), nil
+}
+
+// GetParameter fetches the setting with the key, its name is the full key.
+func (cli *AppConfigClient) GetParameter(key string) (*Parameter, *SSMError) {
+	ctx, span := startSpan(cli.ctx, "AppConfigClient.Get", attribute.String("appconfig.key", key))
+	p, err := cli.WithContext(ctx).getParameter(key)
+	endSpan(span, err)
+	return p, err
+}
+
+func (cli *AppConfigClient) getParameter(key string) (*Parameter, *SSMError) {
+	if cli.cache == nil {
+		p, _, err := cli.fetchParameter(key, nil)
+		return p, err
+	}
+
+	params, err := cli.cache.get(cacheKey{store: cli.throttle.store, filter: key, label: cli.label}, func(stale []Parameter) ([]Parameter, bool, *SSMError) {
+		var etag *azcore.ETag
+		if len(stale) == 1 && stale[0].ETag != "" {
+			etag = to.Ptr(azcore.ETag(stale[0].ETag))
+		}
+		p, unchanged, err := cli.fetchParameter(key, etag)
+		if err != nil || unchanged {
+			return nil, unchanged, err
+		}
+		return []Parameter{*p}, false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &params[0], nil
+}
+
+// fetchParameter reads the setting, if etag is set it reports whether the
+// setting is unchanged instead of reading it again.
+func (cli *AppConfigClient) fetchParameter(key string, etag *azcore.ETag) (*Parameter, bool, *SSMError) {
 
 	resp, err := cli.Client.GetSetting(
 		cli.ctx,
-		key, nil)
+		key, &azappconfig.GetSettingOptions{Label: cli.labelPtr(), OnlyIfChanged: etag})
 
 	if err != nil {
-		return nil, &SSMError{Err: err}
+		if etag != nil && hasStatusCode(err, http.StatusNotModified) {
+			return nil, true, nil
+		}
+		return nil, false, &SSMError{Err: Classify(err)}
 	}
 
 	if resp.Key == nil {
-		return nil, NewSSMError("Key not found")
+		return nil, false, newClassifiedError(ErrNotFound, "Key not found")
 	}
 
-	return map[string]string{*resp.Key: *resp.Value}, nil
+	p := newParameter(*resp.Key, resp.Setting)
+	return &p, false, nil
 }
 
 func (cli *AppConfigClient) List(key string) (map[string]string, *SSMError) {
-	revPgr := cli.Client.NewListRevisionsPager(
-		azappconfig.SettingSelector{
-			KeyFilter: to.Ptr(key),
-			Fields:    azappconfig.AllSettingFields(),
-		},
-		nil)
+	params, err := cli.ListParameters(key)
+	if err != nil {
+		return nil, err
+	}
+	return Values(params), nil
+}
+
+// ListParameters fetches the latest revision of all settings matching the key
+// filter, their names are derived from the keys by the key mapping.
+func (cli *AppConfigClient) ListParameters(key string) ([]Parameter, *SSMError) {
+	ctx, span := startSpan(cli.ctx, "AppConfigClient.List", attribute.String("appconfig.key_filter", key))
+	params, err := cli.WithContext(ctx).listParametersCached(key)
+	if err == nil {
+		// The cached parameters are shared, so they are named on a copy.
+		named := make([]Parameter, len(params))
+		for i, p := range params {
+			p.Name = v1alpha1.MapKey(cli.keyMapping, p.Key)
+			named[i] = p
+		}
+		params = named
+	}
+	span.SetAttributes(attribute.Int("appconfig.settings", len(params)))
+	endSpan(span, err)
+	return params, err
+}
+
+func (cli *AppConfigClient) listParametersCached(key string) ([]Parameter, *SSMError) {
+	if cli.cache == nil {
+		return cli.listParameters(key)
+	}
+	// A list can't be revalidated by a single ETag, so it is fetched again once expired.
+	return cli.cache.get(cacheKey{store: cli.throttle.store, filter: key, label: cli.label, list: true}, func([]Parameter) ([]Parameter, bool, *SSMError) {
+		params, err := cli.listParameters(key)
+		return params, false, err
+	})
+}
+
+func (cli *AppConfigClient) listParameters(key string) ([]Parameter, *SSMError) {
+	selector := azappconfig.SettingSelector{
+		KeyFilter: to.Ptr(key),
+		Fields:    azappconfig.AllSettingFields(),
+	}
+	if cli.label != "" {
+		selector.LabelFilter = to.Ptr(escapeKeyFilter(cli.label))
+	}
+	revPgr := cli.Client.NewListRevisionsPager(selector, nil)
 
-	m := make(map[string]string) // New empty set
+	seen := make(map[string]bool) // New empty set
+	var params []Parameter
 
 	for revPgr.More() {
 		revResp, revErr := revPgr.NextPage(cli.ctx)
 		if revErr != nil {
-			return nil, &SSMError{Err: revErr}
+			return nil, &SSMError{Err: Classify(revErr)}
 		}
 		for _, setting := range revResp.Settings {
-			if _, ok := m[*setting.Key]; ok {
+			// revisions are returned newest first
+			if seen[*setting.Key] {
 				continue
 			}
-			ss := strings.Split(*setting.Key, "/")
-			name := strings.ToUpper(ss[len(ss)-1])
-			name = strings.ReplaceAll(name, "-", "_")
-			m[name] = *setting.Value
+			seen[*setting.Key] = true
+			params = append(params, newParameter(*setting.Key, setting))
 		}
 	}
-	return m, nil
+	return params, nil
+}
+
+// ErrorAnnotation is the annotation of the Secret holding the error of the
+// setting of its key name.
+func ErrorAnnotation(name string) string {
+	return fmt.Sprintf("ssm.aws/%s_error", name)
 }
 
 func (cli *AppConfigClient) FetchParametersStoreValues(refs []v1alpha1.ParametersStoreRef) (map[string]string, map[string]string, *SSMError) {
+	params, anno, err := cli.FetchParameters(refs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return Values(params), anno, nil
+}
+
+// FetchParameters fetches the settings of the refs with their metadata and
+// resolves their Key Vault references. If some refs fail, the parameters of
+// the others are returned with the error.
+func (cli *AppConfigClient) FetchParameters(refs []v1alpha1.ParametersStoreRef) ([]Parameter, map[string]string, *SSMError) {
 
-	dict := make(map[string]string)
+	params := make([]Parameter, 0, len(refs))
 	anno := make(map[string]string)
 	errors := make([]ParameterError, 0, len(refs))
 
+	keys := make([]string, 0, len(refs))
+	seen := make(map[string]bool, len(refs))
 	for _, ref := range refs {
-		log.Info("fetching values from SSM Parameter Store", "Key", ref.Key, "Name", ref.Name)
-		got, err := cli.Get(ref.Key)
+		if !seen[ref.Key] {
+			seen[ref.Key] = true
+			keys = append(keys, ref.Key)
+		}
+	}
+	log.Info("fetching values from SSM Parameter Store", "Keys", len(keys))
+	results := cli.fetchKeys(keys)
+
+	for _, ref := range refs {
+		name := ref.Name
+		if name == "" {
+			name = v1alpha1.MapKey(cli.keyMapping, ref.Key)
+		}
+		got := results[ref.Key]
+		var p Parameter
+		err := got.err
+		if err == nil {
+			p = *got.param
+			err = cli.resolveReference(&p)
+		}
 		if err != nil {
 			log.Error(err, "error fetching values from SSM Parameter Store", "Key", ref.Key, "Name", ref.Name)
-			anno[fmt.Sprintf("ssm.aws/%s_error", ref.Name)] = err.Error()
-			errors = append(errors, ParameterError{Name: ref.Name, Err: err})
+			anno[ErrorAnnotation(name)] = err.Error()
+			errors = append(errors, ParameterError{Name: ref.Name, Key: ref.Key, Err: err})
 			continue
 			// return nil, nil, err
 		}
-		name := ref.Name
-		for k, v := range got {
-			if name == "" {
-				//TODO make this configurable in the ParameterStore crd
-				ss := strings.Split(k, "/")
-				name = strings.ToUpper(ss[len(ss)-1])
-				name = strings.ReplaceAll(name, "-", "_")
-			}
-			dict[name] = v
-		}
+		p.Name = name
+		params = append(params, p)
 	}
 
 	if len(errors) > 0 {
-		return nil, nil, &SSMError{ParameterErrors: errors}
+		// The fetched parameters are returned as well to sync them partially.
+		return params, anno, &SSMError{ParameterErrors: errors}
 	}
 
-	return dict, anno, nil
+	return params, anno, nil
 }
 
 func (cli *AppConfigClient) SSMParametersValueToSecret(ref []v1alpha1.ParametersStoreRef) (map[string]string, map[string]string, *SSMError) {
@@ -9,7 +9,7 @@ import (
 
 	"github.com/cucumber/godog"
 	"github.com/cucumber/godog/colors"
-	"github.com/fr123k/aws-ssm-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
 	"github.com/spf13/pflag"
 )
 
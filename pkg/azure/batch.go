@@ -0,0 +1,167 @@
+package azure
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxBatchSize is the maximum number of keys App Configuration accepts in a key filter.
+const maxBatchSize = 5
+
+// nullLabel is the label filter matching settings without label, like GetSetting without label does.
+const nullLabel = `\0`
+
+// SetBatching groups up to batchSize exact keys into one list request and runs
+// up to concurrency requests in parallel when fetching several keys. A batchSize
+// below 2 fetches every key with its own request.
+func (cli *AppConfigClient) SetBatching(batchSize, concurrency int) {
+	if batchSize > maxBatchSize {
+		batchSize = maxBatchSize
+	}
+	cli.batchSize = batchSize
+	cli.concurrency = concurrency
+}
+
+// fetchResult is the parameter or the error of a single key.
+type fetchResult struct {
+	param *Parameter
+	err   *SSMError
+}
+
+// fetchKeys fetches the keys in batches and with bounded concurrency. Keys a
+// batch didn't return are fetched on their own to report their error.
+func (cli *AppConfigClient) fetchKeys(keys []string) map[string]fetchResult {
+	results := make(map[string]fetchResult, len(keys))
+	var mu sync.Mutex
+	set := func(key string, r fetchResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		results[key] = r
+	}
+
+	g := errgroup.Group{}
+	concurrency := cli.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	g.SetLimit(concurrency)
+
+	get := func(key string) {
+		p, err := cli.GetParameter(key)
+		set(key, fetchResult{param: p, err: err})
+	}
+
+	if cli.batchSize < 2 {
+		for _, key := range keys {
+			key := key
+			g.Go(func() error { get(key); return nil })
+		}
+		_ = g.Wait()
+		return results
+	}
+
+	for _, batch := range batches(keys, cli.batchSize) {
+		batch := batch
+		g.Go(func() error {
+			params, err := cli.listKeys(batch)
+			if err != nil {
+				for _, key := range batch {
+					set(key, fetchResult{err: err})
+				}
+				return nil
+			}
+			found := make(map[string]bool, len(params))
+			for i := range params {
+				found[params[i].Key] = true
+				set(params[i].Key, fetchResult{param: &params[i]})
+			}
+			for _, key := range batch {
+				if !found[key] {
+					// The own request reports the error of the missing key.
+					get(key)
+				}
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return results
+}
+
+// listKeys fetches the settings with the label of the client, or without label,
+// of the exact keys with one list request.
+func (cli *AppConfigClient) listKeys(keys []string) ([]Parameter, *SSMError) {
+	ctx, span := startSpan(cli.ctx, "AppConfigClient.List", attribute.StringSlice("appconfig.keys", keys))
+	params, err := cli.WithContext(ctx).listBatch(keys)
+	span.SetAttributes(attribute.Int("appconfig.settings", len(params)))
+	endSpan(span, err)
+	return params, err
+}
+
+func (cli *AppConfigClient) listBatch(keys []string) ([]Parameter, *SSMError) {
+	filters := make([]string, len(keys))
+	for i, key := range keys {
+		filters[i] = escapeKeyFilter(key)
+	}
+	filter := strings.Join(filters, ",")
+
+	if cli.cache == nil {
+		return cli.listSettings(filter)
+	}
+	return cli.cache.get(cacheKey{store: cli.throttle.store, filter: filter, label: cli.labelFilter(), list: true}, func([]Parameter) ([]Parameter, bool, *SSMError) {
+		params, err := cli.listSettings(filter)
+		return params, false, err
+	})
+}
+
+func (cli *AppConfigClient) listSettings(filter string) ([]Parameter, *SSMError) {
+	pager := cli.Client.NewListSettingsPager(
+		azappconfig.SettingSelector{
+			KeyFilter:   to.Ptr(filter),
+			LabelFilter: to.Ptr(cli.labelFilter()),
+			Fields:      azappconfig.AllSettingFields(),
+		},
+		nil)
+
+	var params []Parameter
+	for pager.More() {
+		page, err := pager.NextPage(cli.ctx)
+		if err != nil {
+			return nil, &SSMError{Err: Classify(err)}
+		}
+		for _, setting := range page.Settings {
+			params = append(params, newParameter(*setting.Key, setting))
+		}
+	}
+	return params, nil
+}
+
+// labelFilter matches the label of the client, or settings without label.
+func (cli *AppConfigClient) labelFilter() string {
+	if cli.label == "" {
+		return nullLabel
+	}
+	return escapeKeyFilter(cli.label)
+}
+
+// escapeKeyFilter escapes the characters with a special meaning in key filters.
+func escapeKeyFilter(key string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `,`, `\,`).Replace(key)
+}
+
+// batches splits the keys into batches of up to size keys.
+func batches(keys []string, size int) [][]string {
+	var result [][]string
+	for size < len(keys) {
+		keys, result = keys[size:], append(result, keys[:size])
+	}
+	if len(keys) > 0 {
+		result = append(result, keys)
+	}
+	return result
+}
@@ -0,0 +1,136 @@
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+var unescapedComma = regexp.MustCompile(`([^\\]),`)
+
+func batchTestServer(t *testing.T, values map[string]string) (*int32, *int32) {
+	var lists, gets int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Sync-Token", "id=value;sn=0")
+		if req.URL.Path == "/kv" {
+			atomic.AddInt32(&lists, 1)
+			assert.Equal(t, nullLabel, req.URL.Query().Get("label"))
+			var items []string
+			for _, key := range strings.Split(unescapedComma.ReplaceAllString(req.URL.Query().Get("key"), "$1\x00"), "\x00") {
+				key = strings.NewReplacer(`\,`, `,`, `\*`, `*`, `\\`, `\`).Replace(key)
+				if value, ok := values[key]; ok {
+					items = append(items, fmt.Sprintf(`{"etag": "etag", "key": %q, "value": %q}`, key, value))
+				}
+			}
+			_, _ = fmt.Fprintf(rw, `{"items": [%s]}`, strings.Join(items, ","))
+			return
+		}
+		atomic.AddInt32(&gets, 1)
+		key := strings.TrimPrefix(req.URL.Path, "/kv/")
+		value, ok := values[key]
+		if !ok {
+			rw.WriteHeader(http.StatusNotFound)
+			_, _ = rw.Write([]byte(`{"message": "The parameter was not found"}`))
+			return
+		}
+		_, _ = fmt.Fprintf(rw, `{"etag": "etag", "key": %q, "value": %q}`, key, value)
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("LOCAL_STACK_ENDPOINT", server.URL)
+	return &lists, &gets
+}
+
+func TestFetchParametersInBatches(t *testing.T) {
+	values := map[string]string{}
+	var refs []v1alpha1.ParametersStoreRef
+	for i := 0; i < 7; i++ {
+		key := fmt.Sprintf("/app/key,%d", i)
+		values[key] = fmt.Sprintf("value%d", i)
+		refs = append(refs, v1alpha1.ParametersStoreRef{Name: fmt.Sprintf("KEY%d", i), Key: key})
+	}
+	delete(values, "/app/key,3")
+	lists, gets := batchTestServer(t, values)
+
+	appConfig, err := NewAppClient(nil)
+	assert.Nil(t, err)
+	appConfig.SetBatching(5, 2)
+
+	_, _, ssmErr := appConfig.FetchParameters(refs)
+	assert.NotNil(t, ssmErr)
+	assert.Len(t, ssmErr.ParameterErrors, 1)
+	assert.Equal(t, "KEY3", ssmErr.ParameterErrors[0].Name)
+	assert.ErrorIs(t, ssmErr, ErrNotFound)
+	// Two batches and the missing key on its own.
+	assert.Equal(t, int32(2), atomic.LoadInt32(lists))
+	assert.Equal(t, int32(1), atomic.LoadInt32(gets))
+
+	existing := append(append([]v1alpha1.ParametersStoreRef{}, refs[:3]...), refs[4:]...)
+	params, _, ssmErr := appConfig.FetchParameters(existing)
+	assert.Nil(t, ssmErr)
+	assert.Len(t, params, 6)
+	for i, p := range params {
+		assert.Equal(t, existing[i].Name, p.Name)
+		assert.Equal(t, values[existing[i].Key], p.Value)
+	}
+}
+
+func TestBatches(t *testing.T) {
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}, batches([]string{"a", "b", "c", "d", "e"}, 2))
+	assert.Equal(t, [][]string{{"a", "b"}}, batches([]string{"a", "b"}, 10))
+	assert.Nil(t, batches(nil, 10))
+}
+
+func TestEscapeKeyFilter(t *testing.T) {
+	assert.Equal(t, `/app/a\,b\*c\\d`, escapeKeyFilter(`/app/a,b*c\d`))
+}
+
+func TestSSMGetParameters(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		body, _ := io.ReadAll(req.Body)
+		var in struct{ Names []string }
+		assert.Nil(t, json.Unmarshal(body, &in))
+		assert.LessOrEqual(t, len(in.Names), ssmBatchSize)
+
+		var params, invalid []string
+		for _, name := range in.Names {
+			if name == "/app/missing" {
+				invalid = append(invalid, fmt.Sprintf("%q", name))
+				continue
+			}
+			params = append(params, fmt.Sprintf(`{"Name": %q, "Type": "String", "Value": "value of %s"}`, name, name))
+		}
+		rw.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_, _ = fmt.Fprintf(rw, `{"Parameters": [%s], "InvalidParameters": [%s]}`, strings.Join(params, ","), strings.Join(invalid, ","))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("LOCAL_STACK_ENDPOINT", server.URL)
+
+	refs := []v1alpha1.ParametersStoreRef{}
+	for i := 0; i < 12; i++ {
+		refs = append(refs, v1alpha1.ParametersStoreRef{Key: fmt.Sprintf("/app/key-%d", i)})
+	}
+	ssm := NewSSMClient(nil)
+
+	result, _, err := ssm.FetchParametersStoreValues(refs)
+	assert.Nil(t, err)
+	assert.Len(t, result, 12)
+	assert.Equal(t, "value of /app/key-11", result["KEY_11"])
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	_, _, err = ssm.FetchParametersStoreValues(append(refs, v1alpha1.ParametersStoreRef{Name: "MISSING", Key: "/app/missing"}))
+	assert.NotNil(t, err)
+	assert.Len(t, err.ParameterErrors, 1)
+	assert.Equal(t, "MISSING", err.ParameterErrors[0].Name)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,129 @@
+package azure
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/singleflight"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "appconfig_cache_requests_total",
+	Help: "Number of App Configuration lookups by cache result: hit, miss or revalidated.",
+}, []string{"store", "result"})
+
+func init() {
+	metrics.Registry.MustRegister(cacheRequests)
+}
+
+type cacheKey struct {
+	store  string
+	filter string
+	label  string
+	list   bool
+}
+
+func (k cacheKey) String() string {
+	kind := "get"
+	if k.list {
+		kind = "list"
+	}
+	return kind + "\x00" + k.store + "\x00" + k.filter + "\x00" + k.label
+}
+
+// revalidationWindow is how long expired single keys are kept to revalidate
+// them by their ETag. Keys not read again within it are evicted.
+const revalidationWindow = 10 * time.Minute
+
+type cacheEntry struct {
+	params  []Parameter
+	fetched time.Time
+}
+
+// cache holds the fetched parameters shared by all ParameterStores reading
+// from a store. Concurrent misses of the same key are coalesced into one request.
+type cache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[cacheKey]*cacheEntry
+	swept   time.Time
+	group   singleflight.Group
+}
+
+func newCache(ttl time.Duration) *cache {
+	return &cache{ttl: ttl, entries: map[cacheKey]*cacheEntry{}}
+}
+
+// get returns the cached parameters of the key. Expired single keys are passed
+// to fetch for revalidation, fetch reports if they are unchanged. Lists have no
+// ETag of their own and aren't revalidated, they are fetched again once expired.
+func (c *cache) get(key cacheKey, fetch func(stale []Parameter) ([]Parameter, bool, *SSMError)) ([]Parameter, *SSMError) {
+	c.mu.Lock()
+	c.sweep(time.Now())
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && time.Since(entry.fetched) < c.ttl {
+		cacheRequests.WithLabelValues(key.store, "hit").Inc()
+		return copyParameters(entry.params), nil
+	}
+
+	v, err, _ := c.group.Do(key.String(), func() (interface{}, error) {
+		var stale []Parameter
+		if ok {
+			stale = entry.params
+		}
+		params, unchanged, err := fetch(stale)
+		if err != nil {
+			return nil, err
+		}
+		result := "miss"
+		if unchanged && ok {
+			params = stale
+			result = "revalidated"
+		}
+		cacheRequests.WithLabelValues(key.store, result).Inc()
+
+		c.mu.Lock()
+		c.entries[key] = &cacheEntry{params: params, fetched: time.Now()}
+		c.mu.Unlock()
+		return params, nil
+	})
+	if err != nil {
+		c.mu.Lock()
+		delete(c.entries, key)
+		c.mu.Unlock()
+		return nil, err.(*SSMError)
+	}
+	return copyParameters(v.([]Parameter)), nil
+}
+
+// sweep evicts the expired lists and the single keys expired for longer than
+// the revalidation window, at most once per ttl. The caller holds the lock.
+func (c *cache) sweep(now time.Time) {
+	if now.Sub(c.swept) < c.ttl {
+		return
+	}
+	c.swept = now
+	for key, entry := range c.entries {
+		age := now.Sub(entry.fetched)
+		if age >= c.ttl && (key.list || age >= c.ttl+revalidationWindow) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func copyParameters(params []Parameter) []Parameter {
+	return append([]Parameter(nil), params...)
+}
+
+// SetCacheTTL caches the fetched values for the ttl, shared between all
+// callers of the client. A ttl of 0 disables the cache.
+func (cli *AppConfigClient) SetCacheTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		cli.cache = nil
+		return
+	}
+	cli.cache = newCache(ttl)
+}
@@ -0,0 +1,128 @@
+package azure
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func cacheTestServer(t *testing.T, delay time.Duration) (*int32, *string) {
+	var requests int32
+	value := "dbuser"
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(delay)
+		etag := "etag-" + value
+		rw.Header().Set("Sync-Token", "id=value;sn=0")
+		if req.Header.Get("If-None-Match") == `"`+etag+`"` {
+			rw.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if req.URL.Path == "/revisions" {
+			_, _ = fmt.Fprintf(rw, `{"items": [{"etag": %q, "key": "/app/user", "value": %q}]}`, etag, value)
+			return
+		}
+		_, _ = fmt.Fprintf(rw, `{"etag": %q, "key": "user", "value": %q}`, etag, value)
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("LOCAL_STACK_ENDPOINT", server.URL)
+	return &requests, &value
+}
+
+func TestCacheHit(t *testing.T) {
+	requests, _ := cacheTestServer(t, 0)
+	appConfig, err := NewAppClient(nil)
+	assert.Nil(t, err)
+	appConfig.SetCacheTTL(time.Hour)
+
+	hits := cacheRequests.WithLabelValues(appConfig.throttle.store, "hit")
+	before := testutil.ToFloat64(hits)
+
+	for i := 0; i < 3; i++ {
+		p, ssmErr := appConfig.GetParameter("user")
+		assert.Nil(t, ssmErr)
+		assert.Equal(t, "dbuser", p.Value)
+		// Callers can't modify the cached parameters.
+		p.Name = "changed"
+	}
+	values, ssmErr := appConfig.List("/app/*")
+	assert.Nil(t, ssmErr)
+	assert.Equal(t, map[string]string{"USER": "dbuser"}, values)
+	_, ssmErr = appConfig.List("/app/*")
+	assert.Nil(t, ssmErr)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+	assert.Equal(t, before+3, testutil.ToFloat64(hits))
+}
+
+func TestCacheRevalidate(t *testing.T) {
+	requests, value := cacheTestServer(t, 0)
+	appConfig, err := NewAppClient(nil)
+	assert.Nil(t, err)
+	appConfig.SetCacheTTL(time.Nanosecond)
+
+	revalidated := cacheRequests.WithLabelValues(appConfig.throttle.store, "revalidated")
+	before := testutil.ToFloat64(revalidated)
+
+	_, ssmErr := appConfig.GetParameter("user")
+	assert.Nil(t, ssmErr)
+	p, ssmErr := appConfig.GetParameter("user")
+	assert.Nil(t, ssmErr)
+	assert.Equal(t, "dbuser", p.Value)
+	assert.Equal(t, before+1, testutil.ToFloat64(revalidated))
+
+	*value = "admin"
+	p, ssmErr = appConfig.GetParameter("user")
+	assert.Nil(t, ssmErr)
+	assert.Equal(t, "admin", p.Value)
+	assert.Equal(t, int32(3), atomic.LoadInt32(requests))
+}
+
+func TestCacheCoalescesMisses(t *testing.T) {
+	requests, _ := cacheTestServer(t, 50*time.Millisecond)
+	appConfig, err := NewAppClient(nil)
+	assert.Nil(t, err)
+	appConfig.SetCacheTTL(time.Hour)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p, ssmErr := appConfig.GetParameter("user")
+			assert.Nil(t, ssmErr)
+			assert.Equal(t, "dbuser", p.Value)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+}
+
+func TestCacheEvictsExpiredEntries(t *testing.T) {
+	c := newCache(time.Minute)
+	fetch := func([]Parameter) ([]Parameter, bool, *SSMError) { return []Parameter{{Key: "user"}}, false, nil }
+	key := cacheKey{store: "store", filter: "user"}
+	list := cacheKey{store: "store", filter: "user,password", list: true}
+	_, ssmErr := c.get(key, fetch)
+	assert.Nil(t, ssmErr)
+	_, ssmErr = c.get(list, fetch)
+	assert.Nil(t, ssmErr)
+
+	// Expired lists are evicted, single keys are kept for revalidation.
+	now := time.Now()
+	c.sweep(now.Add(2 * time.Minute))
+	assert.Contains(t, c.entries, key)
+	assert.NotContains(t, c.entries, list)
+
+	// Keys not read again within the revalidation window are evicted.
+	c.sweep(now.Add(2*time.Minute + revalidationWindow))
+	assert.Empty(t, c.entries)
+}
@@ -1,11 +1,99 @@
 package azure
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
+
+// Classes of errors returned by the Azure APIs, test for them with errors.Is.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrThrottled    = errors.New("throttled")
+	ErrTransient    = errors.New("transient")
+	ErrInvalidRef   = errors.New("invalid reference")
 )
 
+// ClassifiedError is an error of an Azure API call together with its class.
+// The underlying error, e.g. the *azcore.ResponseError, is available with errors.As.
+type ClassifiedError struct {
+	Class      error
+	StatusCode int
+	Err        error
+}
+
+func (e *ClassifiedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ClassifiedError) Unwrap() []error {
+	return []error{e.Class, e.Err}
+}
+
+// Classify wraps the error of an Azure API call into a ClassifiedError based on
+// the status code of the response. Errors that can't be classified are returned as is.
+func Classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	var classified *ClassifiedError
+	if errors.As(err, &classified) {
+		return err
+	}
+
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		class := classOf(respErr.StatusCode)
+		if class == nil {
+			return err
+		}
+		return &ClassifiedError{Class: class, StatusCode: respErr.StatusCode, Err: err}
+	}
+
+	var authErr *azidentity.AuthenticationFailedError
+	var credErr *azidentity.AuthenticationRequiredError
+	if errors.As(err, &authErr) || errors.As(err, &credErr) {
+		return &ClassifiedError{Class: ErrUnauthorized, Err: err}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return &ClassifiedError{Class: ErrTransient, Err: err}
+	}
+	return err
+}
+
+func classOf(code int) error {
+	switch {
+	case code == http.StatusNotFound:
+		return ErrNotFound
+	case code == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case code == http.StatusForbidden:
+		return ErrForbidden
+	case code == http.StatusTooManyRequests:
+		return ErrThrottled
+	case code == http.StatusBadRequest:
+		return ErrInvalidRef
+	case code == http.StatusRequestTimeout || code >= http.StatusInternalServerError:
+		return ErrTransient
+	}
+	return nil
+}
+
+// newClassifiedError returns an SSMError of the class with the message.
+func newClassifiedError(class error, msg string) *SSMError {
+	return &SSMError{Err: &ClassifiedError{Class: class, Err: errors.New(msg)}}
+}
+
 type SSMError struct {
 	Err             error
 	ParameterErrors []ParameterError
@@ -13,7 +101,9 @@ type SSMError struct {
 
 type ParameterError struct {
 	Name string
-	Err  error
+	// Key is the key of the parameter in the store.
+	Key string
+	Err error
 }
 
 func NewSSMError(msg string) *SSMError {
@@ -32,6 +122,22 @@ func (e *SSMError) Error() string {
 	return b.String()
 }
 
+// Unwrap returns the error and the errors of the single parameters.
+func (e *SSMError) Unwrap() []error {
+	errs := make([]error, 0, len(e.ParameterErrors)+1)
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	for i := range e.ParameterErrors {
+		errs = append(errs, &e.ParameterErrors[i])
+	}
+	return errs
+}
+
 func (e *ParameterError) Error() string {
 	return fmt.Sprintf("%s %s", e.Name, e.Err)
 }
+
+func (e *ParameterError) Unwrap() error {
+	return e.Err
+}
@@ -2,8 +2,14 @@ package azure
 
 import (
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
 	"testing"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -27,3 +33,55 @@ func TestParameterError(t *testing.T) {
 
 	assert.Equal(t, "/ssm/param1 The param /ssm/param1 was not found./ssm/param2 The param /ssm/param2 was not found.", err.Error())
 }
+
+func responseError(code int) error {
+	return runtime.NewResponseError(&http.Response{
+		StatusCode: code,
+		Status:     http.StatusText(code),
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader("{}")),
+		Request:    &http.Request{Method: http.MethodGet, URL: &url.URL{Scheme: "https", Host: "test.azconfig.io", Path: "/kv/key"}},
+	})
+}
+
+func TestClassify(t *testing.T) {
+	for code, class := range map[int]error{
+		http.StatusNotFound:            ErrNotFound,
+		http.StatusUnauthorized:        ErrUnauthorized,
+		http.StatusForbidden:           ErrForbidden,
+		http.StatusTooManyRequests:     ErrThrottled,
+		http.StatusBadRequest:          ErrInvalidRef,
+		http.StatusServiceUnavailable:  ErrTransient,
+		http.StatusInternalServerError: ErrTransient,
+	} {
+		err := Classify(responseError(code))
+		assert.ErrorIs(t, err, class, "status %d", code)
+
+		var respErr *azcore.ResponseError
+		assert.ErrorAs(t, err, &respErr)
+		assert.Equal(t, code, respErr.StatusCode)
+	}
+
+	assert.Nil(t, Classify(nil))
+	unknown := Classify(responseError(http.StatusConflict))
+	assert.NotErrorIs(t, unknown, ErrTransient)
+	assert.Equal(t, responseError(http.StatusConflict).Error(), unknown.Error())
+}
+
+func TestSSMErrorIs(t *testing.T) {
+	err := &SSMError{
+		ParameterErrors: []ParameterError{{
+			Name: "/ssm/param1",
+			Err:  &SSMError{Err: Classify(responseError(http.StatusNotFound))},
+		}},
+	}
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NotErrorIs(t, err, ErrForbidden)
+
+	var classified *ClassifiedError
+	assert.ErrorAs(t, err, &classified)
+	assert.Equal(t, http.StatusNotFound, classified.StatusCode)
+
+	assert.ErrorIs(t, newClassifiedError(ErrInvalidRef, "invalid"), ErrInvalidRef)
+}
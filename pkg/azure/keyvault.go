@@ -0,0 +1,102 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// KeyVaultClient stores values as secrets in an Azure Key Vault
+type KeyVaultClient struct {
+	Client   *azsecrets.Client
+	vaultURL string
+	ctx      context.Context
+}
+
+func NewKeyVaultClient(vaultURL string) (*KeyVaultClient, error) {
+	credential, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	vault := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSuffix(vaultURL, "/"), "https://"), "http://")
+	options := &azsecrets.ClientOptions{
+		ClientOptions: policy.ClientOptions{
+			PerCallPolicies: []policy.Policy{newKeyVaultMetricsPolicy(vault)},
+			TracingProvider: tracingProvider(),
+		},
+	}
+	client, err := azsecrets.NewClient(vaultURL, credential, options)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyVaultClient{Client: client, vaultURL: strings.TrimSuffix(vaultURL, "/"), ctx: context.TODO()}, nil
+}
+
+// DefaultKeyVaultDNSSuffixes are the DNS suffixes of the Key Vaults in the Azure public cloud.
+var DefaultKeyVaultDNSSuffixes = []string{"vault.azure.net"}
+
+var vaultName = regexp.MustCompile(`^[a-zA-Z0-9-]{3,24}$`)
+
+// CheckVaultURL returns an error unless the URL is the https URL of a Key Vault
+// with one of the DNS suffixes, DefaultKeyVaultDNSSuffixes if none are given.
+// The operator sends its Azure credential to it, so nothing else may be used.
+func CheckVaultURL(vaultURL string, suffixes []string) error {
+	if len(suffixes) == 0 {
+		suffixes = DefaultKeyVaultDNSSuffixes
+	}
+	u, err := url.Parse(vaultURL)
+	if err == nil && u.Scheme == "https" && u.User == nil && u.Port() == "" &&
+		(u.Path == "" || u.Path == "/") && u.RawQuery == "" && u.Fragment == "" {
+		for _, suffix := range suffixes {
+			if name, ok := strings.CutSuffix(u.Host, "."+suffix); ok && vaultName.MatchString(name) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("invalid Key Vault URL %q, expected https://<vault>.<%s>", vaultURL, strings.Join(suffixes, "|"))
+}
+
+var invalidSecretNameChars = regexp.MustCompile(`[^0-9a-zA-Z-]+`)
+
+// SecretName derives a valid Key Vault secret name from an App Configuration key.
+func SecretName(key string) string {
+	return strings.Trim(invalidSecretNameChars.ReplaceAllString(key, "-"), "-")
+}
+
+// SetSecret stores the value if it differs from the latest version of the secret
+// and returns the App Configuration Key Vault reference pointing to it.
+func (kv *KeyVaultClient) SetSecret(name, value string) (string, *SSMError) {
+	ctx, span := startSpan(kv.ctx, "KeyVaultClient.SetSecret", attribute.String("keyvault.secret", name))
+	ref, err := kv.WithContext(ctx).setSecret(name, value)
+	endSpan(span, err)
+	return ref, err
+}
+
+func (kv *KeyVaultClient) setSecret(name, value string) (string, *SSMError) {
+	current, err := kv.Client.GetSecret(kv.ctx, name, "", nil)
+	if err != nil && !IsNotFound(err) {
+		return "", &SSMError{Err: Classify(err)}
+	}
+	if err != nil || current.Value == nil || *current.Value != value {
+		_, err = kv.Client.SetSecret(kv.ctx, name, azsecrets.SetSecretParameters{Value: to.Ptr(value)}, nil)
+		if err != nil {
+			return "", &SSMError{Err: Classify(err)}
+		}
+	}
+	return KeyVaultReference(kv.vaultURL, name), nil
+}
+
+// KeyVaultReference returns the value of an App Configuration setting referencing
+// the latest version of the Key Vault secret.
+func KeyVaultReference(vaultURL, name string) string {
+	return fmt.Sprintf(`{"uri":"%s/secrets/%s"}`, strings.TrimSuffix(vaultURL, "/"), name)
+}
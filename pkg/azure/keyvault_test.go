@@ -0,0 +1,35 @@
+package azure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretName(t *testing.T) {
+	assert.Equal(t, "stg-foo-app-db-password", SecretName("/stg/foo-app/db_password"))
+}
+
+func TestKeyVaultReference(t *testing.T) {
+	assert.Equal(t, `{"uri":"https://vault.vault.azure.net/secrets/db"}`, KeyVaultReference("https://vault.vault.azure.net/", "db"))
+}
+
+func TestCheckVaultURL(t *testing.T) {
+	assert.Nil(t, CheckVaultURL("https://my-vault.vault.azure.net", nil))
+	assert.Nil(t, CheckVaultURL("https://my-vault.vault.azure.net/", nil))
+	assert.Nil(t, CheckVaultURL("https://my-vault.vault.azure.cn", []string{"vault.azure.net", "vault.azure.cn"}))
+
+	for _, vaultURL := range []string{
+		"http://my-vault.vault.azure.net",
+		"https://my-vault.vault.azure.cn",
+		"https://attacker.example.com",
+		"https://my-vault.vault.azure.net.example.com",
+		"https://user@my-vault.vault.azure.net",
+		"https://my-vault.vault.azure.net:8443",
+		"https://my-vault.vault.azure.net/secrets",
+		"https://a.b.vault.azure.net",
+		"https://vault.azure.net",
+	} {
+		assert.NotNil(t, CheckVaultURL(vaultURL, nil), vaultURL)
+	}
+}
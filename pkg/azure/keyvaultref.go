@@ -0,0 +1,164 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// KeyVaultResolver resolves the Key Vault references of App Configuration
+// settings to the values of the secrets they point to, with one client per Key Vault.
+type KeyVaultResolver struct {
+	suffixes   []string
+	credential azcore.TokenCredential
+	transport  policy.Transporter
+
+	mu      sync.Mutex
+	clients map[string]*azsecrets.Client
+}
+
+// NewKeyVaultResolver returns a resolver of the references to the Key Vaults
+// with one of the DNS suffixes, DefaultKeyVaultDNSSuffixes if none are given.
+// References to other hosts aren't resolved, the operator would send its Azure
+// credential to them.
+func NewKeyVaultResolver(suffixes []string) (*KeyVaultResolver, error) {
+	credential, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyVaultResolver{suffixes: suffixes, credential: credential}, nil
+}
+
+// SetKeyVaultResolver resolves the Key Vault references read by the client
+// with the resolver, they are returned as read if nil.
+func (cli *AppConfigClient) SetKeyVaultResolver(resolver *KeyVaultResolver) {
+	cli.keyVaults = resolver
+}
+
+// resolveReference replaces the value of a Key Vault reference with the value
+// of the secret. The cached parameters are shared, so p has to be a copy.
+func (cli *AppConfigClient) resolveReference(p *Parameter) *SSMError {
+	if cli.keyVaults == nil || !IsKeyVaultReference(p.ContentType) {
+		return nil
+	}
+	value, err := cli.keyVaults.Resolve(cli.ctx, p.Value)
+	if err != nil {
+		return err
+	}
+	p.Value = value
+	return nil
+}
+
+// resolveReferences resolves the Key Vault references of the parameters in place.
+func (cli *AppConfigClient) resolveReferences(params []Parameter) *SSMError {
+	for i := range params {
+		if err := cli.resolveReference(&params[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsKeyVaultReference reports whether the content type is the one of Key Vault
+// references, ignoring its parameters like the charset.
+func IsKeyVaultReference(contentType string) bool {
+	mediaType := func(ct string) string {
+		mt, _, _ := strings.Cut(ct, ";")
+		return strings.TrimSpace(mt)
+	}
+	return strings.EqualFold(mediaType(contentType), mediaType(KeyVaultRefContentType))
+}
+
+// Resolve returns the value of the secret the Key Vault reference points to,
+// its latest version unless the reference names one.
+func (r *KeyVaultResolver) Resolve(ctx context.Context, reference string) (string, *SSMError) {
+	ctx, span := startSpan(ctx, "KeyVaultResolver.Resolve")
+	vaultURL, name, version, err := parseKeyVaultReference(reference)
+	if err == nil {
+		span.SetAttributes(attribute.String("keyvault.vault", vaultURL), attribute.String("keyvault.secret", name))
+		err = CheckVaultURL(vaultURL, r.suffixes)
+	}
+	if err != nil {
+		ssmErr := newClassifiedError(ErrInvalidRef, err.Error())
+		endSpan(span, ssmErr)
+		return "", ssmErr
+	}
+	value, ssmErr := r.resolve(ctx, vaultURL, name, version)
+	endSpan(span, ssmErr)
+	return value, ssmErr
+}
+
+func (r *KeyVaultResolver) resolve(ctx context.Context, vaultURL, name, version string) (string, *SSMError) {
+	client, err := r.client(vaultURL)
+	if err != nil {
+		return "", &SSMError{Err: err}
+	}
+	resp, err := client.GetSecret(ctx, name, version, nil)
+	if err != nil {
+		return "", &SSMError{Err: Classify(err)}
+	}
+	if resp.Value == nil {
+		return "", newClassifiedError(ErrNotFound, fmt.Sprintf("secret %s of Key Vault %s has no value", name, vaultURL))
+	}
+	return *resp.Value, nil
+}
+
+func (r *KeyVaultResolver) client(vaultURL string) (*azsecrets.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if client, ok := r.clients[vaultURL]; ok {
+		return client, nil
+	}
+	options := &azsecrets.ClientOptions{
+		ClientOptions: policy.ClientOptions{
+			PerCallPolicies: []policy.Policy{newKeyVaultResolutionMetricsPolicy(strings.TrimPrefix(vaultURL, "https://"))},
+			TracingProvider: tracingProvider(),
+			Transport:       r.transport,
+		},
+	}
+	client, err := azsecrets.NewClient(vaultURL, r.credential, options)
+	if err != nil {
+		return nil, err
+	}
+	if r.clients == nil {
+		r.clients = make(map[string]*azsecrets.Client)
+	}
+	r.clients[vaultURL] = client
+	return client, nil
+}
+
+// parseKeyVaultReference returns the URL of the Key Vault, the name and the
+// version of the secret of the reference {"uri":"<vault>/secrets/<name>[/<version>]"}.
+func parseKeyVaultReference(reference string) (string, string, string, error) {
+	var ref struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal([]byte(reference), &ref); err != nil || ref.URI == "" {
+		return "", "", "", fmt.Errorf("invalid Key Vault reference %q", reference)
+	}
+	u, err := url.Parse(ref.URI)
+	if err != nil {
+		return "", "", "", fmt.Errorf("invalid Key Vault reference %q", reference)
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) < 2 || len(segments) > 3 || segments[0] != "secrets" || segments[1] == "" {
+		return "", "", "", fmt.Errorf("invalid Key Vault secret URI %q", ref.URI)
+	}
+	version := ""
+	if len(segments) == 3 {
+		version = segments[2]
+	}
+	// Everything but the path is part of the vault URL, so it's checked with it.
+	u.Path, u.RawPath = "", ""
+	return u.String(), segments[1], version, nil
+}
@@ -0,0 +1,160 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+)
+
+type fakeCredential struct{}
+
+func (fakeCredential) GetToken(context.Context, policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// keyVaultTransport serves the secrets by their URL, after the challenge of the
+// Key Vault authentication.
+type keyVaultTransport map[string]string
+
+func (t keyVaultTransport) Do(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	value, ok := t["https://"+req.URL.Host+"/"+strings.Join(splitPath(req.URL.Path), "/")]
+	switch {
+	case req.Header.Get("Authorization") == "":
+		rec.Header().Set("WWW-Authenticate", `Bearer authorization="https://login.microsoftonline.com/tenant", resource="https://vault.azure.net"`)
+		rec.WriteHeader(http.StatusUnauthorized)
+	case !ok:
+		rec.WriteHeader(http.StatusNotFound)
+		_, _ = rec.WriteString(`{"error": {"code": "SecretNotFound", "message": "secret not found"}}`)
+	default:
+		_ = json.NewEncoder(rec).Encode(map[string]string{"value": value, "id": "https://" + req.URL.Host + req.URL.Path})
+	}
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+func testKeyVaultResolver(secrets map[string]string) *KeyVaultResolver {
+	return &KeyVaultResolver{credential: fakeCredential{}, transport: keyVaultTransport(secrets)}
+}
+
+// referenceTestServer serves the settings with their content type.
+func referenceTestServer(t *testing.T, settings map[string]string, contentTypes map[string]string) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Sync-Token", "id=value;sn=0")
+		setting := func(key string) string {
+			return fmt.Sprintf(`{"etag": "etag", "key": %q, "value": %q, "content_type": %q}`, key, settings[key], contentTypes[key])
+		}
+		if req.URL.Path == "/kv" || req.URL.Path == "/revisions" {
+			var items []string
+			for _, filter := range strings.Split(req.URL.Query().Get("key"), ",") {
+				for key := range settings {
+					if key == filter || (strings.HasSuffix(filter, "*") && strings.HasPrefix(key, strings.TrimSuffix(filter, "*"))) {
+						items = append(items, setting(key))
+					}
+				}
+			}
+			_, _ = fmt.Fprintf(rw, `{"items": [%s]}`, strings.Join(items, ","))
+			return
+		}
+		key := strings.TrimPrefix(req.URL.Path, "/kv/")
+		if _, ok := settings[key]; !ok {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = rw.Write([]byte(setting(key)))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("LOCAL_STACK_ENDPOINT", server.URL)
+}
+
+func TestResolveKeyVaultReferences(t *testing.T) {
+	referenceTestServer(t, map[string]string{
+		"/app/user":     "dbuser",
+		"/app/password": `{"uri":"https://payments.vault.azure.net/secrets/db-password"}`,
+		"/app/pinned":   `{"uri":"https://payments.vault.azure.net/secrets/db-password/v1"}`,
+		"/app/foreign":  `{"uri":"https://attacker.example.com/secrets/db-password"}`,
+		"/app/missing":  `{"uri":"https://payments.vault.azure.net/secrets/missing"}`,
+	}, map[string]string{
+		"/app/password": KeyVaultRefContentType,
+		"/app/pinned":   "application/vnd.microsoft.appconfig.keyvaultref+json",
+		"/app/foreign":  KeyVaultRefContentType,
+		"/app/missing":  KeyVaultRefContentType,
+	})
+
+	appConfig, err := NewAppClient(nil)
+	assert.Nil(t, err)
+	appConfig.SetKeyVaultResolver(testKeyVaultResolver(map[string]string{
+		"https://payments.vault.azure.net/secrets/db-password":    "dbpassword",
+		"https://payments.vault.azure.net/secrets/db-password/v1": "old-dbpassword",
+	}))
+
+	observed := func() uint64 {
+		m := &dto.Metric{}
+		assert.Nil(t, keyVaultResolutionDuration.WithLabelValues("payments.vault.azure.net", "GetSecret").(prometheus.Metric).Write(m))
+		return m.GetHistogram().GetSampleCount()
+	}
+	observedBefore := observed()
+
+	params, _, ssmErr := appConfig.FetchParameters([]v1alpha1.ParametersStoreRef{
+		{Name: "USER", Key: "/app/user"},
+		{Name: "PASSWORD", Key: "/app/password"},
+		{Name: "PINNED", Key: "/app/pinned"},
+		{Name: "FOREIGN", Key: "/app/foreign"},
+		{Name: "MISSING", Key: "/app/missing"},
+	})
+	assert.Equal(t, map[string]string{"USER": "dbuser", "PASSWORD": "dbpassword", "PINNED": "old-dbpassword"}, Values(params))
+	assert.Len(t, ssmErr.ParameterErrors, 2)
+	assert.Equal(t, "FOREIGN", ssmErr.ParameterErrors[0].Name)
+	assert.ErrorIs(t, ssmErr.ParameterErrors[0].Err, ErrInvalidRef)
+	assert.Equal(t, "MISSING", ssmErr.ParameterErrors[1].Name)
+	assert.ErrorIs(t, ssmErr.ParameterErrors[1].Err, ErrNotFound)
+	assert.Greater(t, observed(), observedBefore)
+
+	params, ssmErr = appConfig.ParameterStoreRefParameters(v1alpha1.ParameterStoreRef{Name: "/app/password"})
+	assert.Nil(t, ssmErr)
+	assert.Equal(t, "dbpassword", params[0].Value)
+
+	// A path fails with any of its references.
+	_, ssmErr = appConfig.ParameterStoreRefParameters(v1alpha1.ParameterStoreRef{Path: "/app/"})
+	assert.NotNil(t, ssmErr)
+
+	// Without resolver the references are returned as read.
+	appConfig.SetKeyVaultResolver(nil)
+	params, ssmErr = appConfig.ParameterStoreRefParameters(v1alpha1.ParameterStoreRef{Name: "/app/password"})
+	assert.Nil(t, ssmErr)
+	assert.Equal(t, `{"uri":"https://payments.vault.azure.net/secrets/db-password"}`, params[0].Value)
+}
+
+func TestParseKeyVaultReference(t *testing.T) {
+	vaultURL, name, version, err := parseKeyVaultReference(`{"uri":"https://payments.vault.azure.net/secrets/db/v1"}`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"https://payments.vault.azure.net", "db", "v1"}, []string{vaultURL, name, version})
+
+	vaultURL, _, _, err = parseKeyVaultReference(`{"uri":"https://user@payments.vault.azure.net:8443/secrets/db"}`)
+	assert.Nil(t, err)
+	assert.NotNil(t, CheckVaultURL(vaultURL, nil))
+
+	for _, reference := range []string{
+		`not json`,
+		`{"uri":""}`,
+		`{"uri":"https://payments.vault.azure.net/keys/db"}`,
+		`{"uri":"https://payments.vault.azure.net/secrets/db/v1/extra"}`,
+	} {
+		_, _, _, err := parseKeyVaultReference(reference)
+		assert.NotNil(t, err, reference)
+	}
+}
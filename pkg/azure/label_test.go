@@ -0,0 +1,99 @@
+package azure
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+// labelTestServer returns the setting of every requested key and records the label of the requests.
+func labelTestServer(t *testing.T) func() []string {
+	var mu sync.Mutex
+	var labels []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Sync-Token", "id=value;sn=0")
+		mu.Lock()
+		labels = append(labels, req.URL.Path+"?label="+req.URL.Query().Get("label"))
+		mu.Unlock()
+		switch req.URL.Path {
+		case "/kv":
+			var items []string
+			for _, key := range strings.Split(req.URL.Query().Get("key"), ",") {
+				items = append(items, fmt.Sprintf(`{"key": %q, "value": "batched"}`, key))
+			}
+			_, _ = fmt.Fprintf(rw, `{"items": [%s]}`, strings.Join(items, ","))
+		case "/revisions":
+			_, _ = rw.Write([]byte(`{"items": [{"key": "/app/db-password", "value": "listed"}]}`))
+		default:
+			_, _ = fmt.Fprintf(rw, `{"etag": "etag", "key": %q, "value": "value"}`, req.URL.Path[len("/kv/"):])
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("LOCAL_STACK_ENDPOINT", server.URL)
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), labels...)
+	}
+}
+
+func TestLabel(t *testing.T) {
+	requests := labelTestServer(t)
+	appConfig, err := NewAppClient(nil)
+	assert.Nil(t, err)
+	appConfig.SetCacheTTL(time.Minute)
+
+	_, ssmErr := appConfig.GetParameter("user")
+	assert.Nil(t, ssmErr)
+	_, ssmErr = appConfig.WithLabel("prod").GetParameter("user")
+	assert.Nil(t, ssmErr)
+	_, ssmErr = appConfig.ListParameters("/app/*")
+	assert.Nil(t, ssmErr)
+	_, ssmErr = appConfig.WithLabel("prod").ListParameters("/app/*")
+	assert.Nil(t, ssmErr)
+	appConfig.SetBatching(5, 1)
+	_, _, ssmErr = appConfig.FetchParameters([]v1alpha1.ParametersStoreRef{{Key: "user"}, {Key: "password"}})
+	assert.Nil(t, ssmErr)
+	_, _, ssmErr = appConfig.WithLabel("prod").FetchParameters([]v1alpha1.ParametersStoreRef{{Key: "user"}, {Key: "password"}})
+	assert.Nil(t, ssmErr)
+
+	// Settings of different labels are cached on their own.
+	assert.Equal(t, []string{
+		"/kv/user?label=",
+		"/kv/user?label=prod",
+		"/revisions?label=",
+		"/revisions?label=prod",
+		"/kv?label=" + nullLabel,
+		"/kv?label=prod",
+	}, requests())
+}
+
+func TestKeyMapping(t *testing.T) {
+	labelTestServer(t)
+	appConfig, err := NewAppClient(nil)
+	assert.Nil(t, err)
+	appConfig.SetCacheTTL(time.Minute)
+
+	params, ssmErr := appConfig.ListParameters("/app/*")
+	assert.Nil(t, ssmErr)
+	assert.Equal(t, "DB_PASSWORD", params[0].Name)
+
+	// The cached parameters are named by the key mapping of every client.
+	params, ssmErr = appConfig.WithKeyMapping(v1alpha1.KeyMappingFullKey).ListParameters("/app/*")
+	assert.Nil(t, ssmErr)
+	assert.Equal(t, "app_db-password", params[0].Name)
+	params, ssmErr = appConfig.ListParameters("/app/*")
+	assert.Nil(t, ssmErr)
+	assert.Equal(t, "DB_PASSWORD", params[0].Name)
+
+	params, _, ssmErr = appConfig.WithKeyMapping(v1alpha1.KeyMappingLastSegment).FetchParameters([]v1alpha1.ParametersStoreRef{{Key: "/app/db-user"}, {Name: "USER", Key: "/app/user"}})
+	assert.Nil(t, ssmErr)
+	assert.Equal(t, []string{"db-user", "USER"}, []string{params[0].Name, params[1].Name})
+}
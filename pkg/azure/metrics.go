@@ -0,0 +1,123 @@
+package azure
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	appConfigRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "appconfig_requests_total",
+		Help: "Number of App Configuration requests by operation and status code.",
+	}, []string{"store", "operation", "code"})
+	appConfigRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "appconfig_request_duration_seconds",
+		Help:    "Latency of App Configuration requests by operation, including retries.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"store", "operation"})
+	keyVaultWriteRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "keyvault_write_requests_total",
+		Help: "Number of Key Vault requests writing pushed secrets by operation and status code.",
+	}, []string{"vault", "operation", "code"})
+	keyVaultWriteRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "keyvault_write_request_duration_seconds",
+		Help:    "Latency of Key Vault requests writing pushed secrets by operation, including retries.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"vault", "operation"})
+	keyVaultResolutionRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "keyvault_resolution_requests_total",
+		Help: "Number of Key Vault requests resolving Key Vault references by operation and status code.",
+	}, []string{"vault", "operation", "code"})
+	keyVaultResolutionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "keyvault_resolution_duration_seconds",
+		Help:    "Latency of Key Vault requests resolving Key Vault references by operation, including retries.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"vault", "operation"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(appConfigRequests, appConfigRequestDuration, keyVaultWriteRequests, keyVaultWriteRequestDuration,
+		keyVaultResolutionRequests, keyVaultResolutionDuration)
+}
+
+// metricsPolicy is a pipeline policy observing the latency and the status code
+// of every call, the latency includes the retries of the call.
+type metricsPolicy struct {
+	host      string
+	requests  *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+	operation func(method string, segments []string) string
+}
+
+func newAppConfigMetricsPolicy(store string) *metricsPolicy {
+	return &metricsPolicy{host: store, requests: appConfigRequests, duration: appConfigRequestDuration, operation: appConfigOperation}
+}
+
+func newKeyVaultMetricsPolicy(vault string) *metricsPolicy {
+	return &metricsPolicy{host: vault, requests: keyVaultWriteRequests, duration: keyVaultWriteRequestDuration, operation: keyVaultOperation}
+}
+
+func newKeyVaultResolutionMetricsPolicy(vault string) *metricsPolicy {
+	return &metricsPolicy{host: vault, requests: keyVaultResolutionRequests, duration: keyVaultResolutionDuration, operation: keyVaultOperation}
+}
+
+func (p *metricsPolicy) Do(req *policy.Request) (*http.Response, error) {
+	raw := req.Raw()
+	operation := p.operation(raw.Method, splitPath(raw.URL.Path))
+
+	start := time.Now()
+	resp, err := req.Next()
+	p.duration.WithLabelValues(p.host, operation).Observe(time.Since(start).Seconds())
+
+	code := "error"
+	if resp != nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	p.requests.WithLabelValues(p.host, operation, code).Inc()
+	return resp, err
+}
+
+// appConfigOperation names the App Configuration operation of the request path.
+func appConfigOperation(method string, segments []string) string {
+	switch segments[0] {
+	case "kv":
+		switch {
+		case len(segments) == 1 && method == http.MethodGet:
+			return "ListSettings"
+		case method == http.MethodGet || method == http.MethodHead:
+			return "GetSetting"
+		case method == http.MethodPut:
+			return "SetSetting"
+		case method == http.MethodDelete:
+			return "DeleteSetting"
+		}
+	case "revisions":
+		return "ListRevisions"
+	case "locks":
+		return "SetReadOnly"
+	}
+	return "Other"
+}
+
+// keyVaultOperation names the Key Vault operation of the request path.
+func keyVaultOperation(method string, segments []string) string {
+	if segments[0] == "secrets" && len(segments) > 1 {
+		switch method {
+		case http.MethodGet:
+			return "GetSecret"
+		case http.MethodPut:
+			return "SetSecret"
+		}
+	}
+	return "Other"
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
@@ -0,0 +1,67 @@
+package azure
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Sync-Token", "id=value;sn=0")
+		if req.URL.Path == "/kv/missing" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = rw.Write([]byte(`{"key": "user", "value": "dbuser"}`))
+	}))
+	defer server.Close()
+	t.Setenv("LOCAL_STACK_ENDPOINT", server.URL)
+
+	appConfig, err := NewAppClient(nil)
+	assert.Nil(t, err)
+	store := appConfig.throttle.store
+
+	ok := appConfigRequests.WithLabelValues(store, "GetSetting", "200")
+	notFound := appConfigRequests.WithLabelValues(store, "GetSetting", "404")
+	okBefore, notFoundBefore := testutil.ToFloat64(ok), testutil.ToFloat64(notFound)
+	observed := func() uint64 {
+		m := &dto.Metric{}
+		assert.Nil(t, appConfigRequestDuration.WithLabelValues(store, "GetSetting").(prometheus.Metric).Write(m))
+		return m.GetHistogram().GetSampleCount()
+	}
+	observedBefore := observed()
+
+	_, ssmErr := appConfig.GetParameter("user")
+	assert.Nil(t, ssmErr)
+	_, ssmErr = appConfig.GetParameter("missing")
+	assert.ErrorIs(t, ssmErr, ErrNotFound)
+
+	assert.Equal(t, okBefore+1, testutil.ToFloat64(ok))
+	assert.Equal(t, notFoundBefore+1, testutil.ToFloat64(notFound))
+	assert.Equal(t, observedBefore+2, observed())
+}
+
+func TestOperation(t *testing.T) {
+	for _, tc := range []struct {
+		method, path, appConfig, keyVault string
+	}{
+		{http.MethodGet, "/kv/user", "GetSetting", "Other"},
+		{http.MethodGet, "/kv", "ListSettings", "Other"},
+		{http.MethodPut, "/kv/user", "SetSetting", "Other"},
+		{http.MethodDelete, "/kv/user", "DeleteSetting", "Other"},
+		{http.MethodGet, "/revisions", "ListRevisions", "Other"},
+		{http.MethodPut, "/locks/user", "SetReadOnly", "Other"},
+		{http.MethodGet, "/secrets/user/", "Other", "GetSecret"},
+		{http.MethodPut, "/secrets/user", "Other", "SetSecret"},
+	} {
+		segments := splitPath(tc.path)
+		assert.Equal(t, tc.appConfig, appConfigOperation(tc.method, segments), tc.path)
+		assert.Equal(t, tc.keyVault, keyVaultOperation(tc.method, segments), tc.path)
+	}
+}
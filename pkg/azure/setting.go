@@ -0,0 +1,95 @@
+package azure
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+)
+
+// KeyVaultRefContentType is the content type of App Configuration settings referencing a Key Vault secret.
+const KeyVaultRefContentType = v1alpha1.KeyVaultRefContentType
+
+// IsNotFound reports whether the error is a 404 response of the Azure API.
+func IsNotFound(err error) bool {
+	return hasStatusCode(err, http.StatusNotFound)
+}
+
+// IsPreconditionFailed reports whether the error is a 412 response of the Azure API,
+// returned if the ETag of a setting doesn't match.
+func IsPreconditionFailed(err error) bool {
+	return hasStatusCode(err, http.StatusPreconditionFailed)
+}
+
+func hasStatusCode(err error, code int) bool {
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == code
+}
+
+func label(label string) *string {
+	if label == "" {
+		return nil
+	}
+	return to.Ptr(label)
+}
+
+// GetSetting returns the setting with the key and label or nil if it doesn't exist.
+func (cli *AppConfigClient) GetSetting(key, lbl string) (*azappconfig.Setting, *SSMError) {
+	resp, err := cli.Client.GetSetting(cli.ctx, key, &azappconfig.GetSettingOptions{Label: label(lbl)})
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, &SSMError{Err: Classify(err)}
+	}
+	return &resp.Setting, nil
+}
+
+// SetSetting writes the setting and returns its new ETag. If etag is set the
+// setting is only overwritten if it wasn't changed since, an empty etag
+// creates the setting only if it doesn't exist yet.
+func (cli *AppConfigClient) SetSetting(key, lbl, value, contentType string, etag *azcore.ETag) (azcore.ETag, *SSMError) {
+	var setting azappconfig.Setting
+	if etag == nil {
+		resp, err := cli.Client.AddSetting(cli.ctx, key, to.Ptr(value), &azappconfig.AddSettingOptions{
+			Label:       label(lbl),
+			ContentType: label(contentType),
+		})
+		if err != nil {
+			return "", &SSMError{Err: Classify(err)}
+		}
+		setting = resp.Setting
+	} else {
+		resp, err := cli.Client.SetSetting(cli.ctx, key, to.Ptr(value), &azappconfig.SetSettingOptions{
+			Label:           label(lbl),
+			ContentType:     label(contentType),
+			OnlyIfUnchanged: etag,
+		})
+		if err != nil {
+			return "", &SSMError{Err: Classify(err)}
+		}
+		setting = resp.Setting
+	}
+	if setting.ETag == nil {
+		return "", nil
+	}
+	return *setting.ETag, nil
+}
+
+// DeleteSetting deletes the setting if it wasn't changed since etag.
+// A not existing setting isn't an error.
+func (cli *AppConfigClient) DeleteSetting(key, lbl string, etag azcore.ETag) *SSMError {
+	opts := &azappconfig.DeleteSettingOptions{Label: label(lbl)}
+	if etag != "" {
+		opts.OnlyIfUnchanged = &etag
+	}
+	_, err := cli.Client.DeleteSetting(cli.ctx, key, opts)
+	if err != nil && !IsNotFound(err) {
+		return &SSMError{Err: Classify(err)}
+	}
+	return nil
+}
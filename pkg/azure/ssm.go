@@ -5,15 +5,17 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
-	"github.com/fr123k/aws-ssm-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
 
 	errs "github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -73,26 +75,30 @@ func (c *SSMClient) FetchParametersStoreValues(refs []v1alpha1.ParametersStoreRe
 	anno := make(map[string]string)
 	errors := make([]ParameterError, 0, len(refs))
 
+	keys := make([]string, 0, len(refs))
+	seen := make(map[string]bool, len(refs))
 	for _, ref := range refs {
-		log.Info("fetching values from SSM Parameter Store", "Key", ref.Key, "Name", ref.Name)
-		got, err := c.GetParameterByName(ref.Key)
-		if err != nil {
+		if !seen[ref.Key] {
+			seen[ref.Key] = true
+			keys = append(keys, ref.Key)
+		}
+	}
+	log.Info("fetching values from SSM Parameter Store", "Keys", len(keys))
+	values, keyErrors := c.GetParameters(keys)
+
+	for _, ref := range refs {
+		name := ref.Name
+		if name == "" {
+			name = SecretKey(ref.Key)
+		}
+		if err, ok := keyErrors[ref.Key]; ok {
 			log.Error(err, "error fetching values from SSM Parameter Store", "Key", ref.Key, "Name", ref.Name)
-			anno[fmt.Sprintf("ssm.aws/%s_error", ref.Name)] = err.Error()
-			errors = append(errors, ParameterError{Name: ref.Name, Err: err})
+			anno[ErrorAnnotation(name)] = err.Error()
+			errors = append(errors, ParameterError{Name: ref.Name, Key: ref.Key, Err: err})
 			continue
 			// return nil, nil, err
 		}
-		name := ref.Name
-		for k, v := range got {
-			if name == "" {
-				//TODO make this configurable in the ParameterStore crd
-				ss := strings.Split(k, "/")
-				name = strings.ToUpper(ss[len(ss)-1])
-				name = strings.ReplaceAll(name, "-", "_")
-			}
-			dict[name] = v
-		}
+		dict[name] = values[ref.Key]
 	}
 
 	if len(errors) > 0 {
@@ -102,6 +108,51 @@ func (c *SSMClient) FetchParametersStoreValues(refs []v1alpha1.ParametersStoreRe
 	return dict, anno, nil
 }
 
+// ssmBatchSize is the maximum number of names GetParameters accepts.
+const ssmBatchSize = 10
+
+// ssmConcurrency is the maximum number of GetParameters requests running in parallel.
+const ssmConcurrency = 4
+
+// GetParameters fetches the parameters in batches of 10 names. Parameters that
+// don't exist or failed to be fetched are returned with their error.
+func (c *SSMClient) GetParameters(names []string) (map[string]string, map[string]*SSMError) {
+	values := make(map[string]string, len(names))
+	keyErrors := make(map[string]*SSMError)
+	var mu sync.Mutex
+
+	g := errgroup.Group{}
+	g.SetLimit(ssmConcurrency)
+	for _, batch := range batches(names, ssmBatchSize) {
+		batch := batch
+		g.Go(func() error {
+			log.Info("fetching values from SSM Parameter Store by names", "Names", len(batch))
+			got, err := c.Ssm.GetParameters(c.ctx, &ssm.GetParametersInput{
+				Names:          batch,
+				WithDecryption: aws.Bool(true),
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				for _, name := range batch {
+					keyErrors[name] = &SSMError{Err: err}
+				}
+				return nil
+			}
+			for _, p := range got.Parameters {
+				values[*p.Name] = *p.Value
+			}
+			for _, name := range got.InvalidParameters {
+				keyErrors[name] = newClassifiedError(ErrNotFound, fmt.Sprintf("parameter %s not found", name))
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return values, keyErrors
+}
+
 func (c *SSMClient) SSMParametersValueToSecret(ref []v1alpha1.ParametersStoreRef) (map[string]string, map[string]string, *SSMError) {
 	params, anno, err := c.FetchParametersStoreValues(ref)
 	if err != nil {
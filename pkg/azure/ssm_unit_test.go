@@ -10,7 +10,7 @@ import (
 	"testing"
 
 	_ "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/fr123k/aws-ssm-operator/api/v1alpha1"
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -260,3 +260,18 @@ func TestSSMParameterValueToSecretByNotFoundPath(t *testing.T) {
 
 	assert.Equal(t, "operation error SSM: GetParametersByPath, https response error StatusCode: 400, RequestID: , api error ParameterNotFound: the parameter path path not found", err.Error())
 }
+
+func TestEndpoint(t *testing.T) {
+	t.Setenv("LOCAL_STACK_ENDPOINT", "")
+	name := "team-store"
+	ep, err := endpoint(&name)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://team-store.azconfig.io", ep)
+
+	// Names that would send the requests to another host are rejected.
+	name = "evil.example/x?"
+	_, err = endpoint(&name)
+	assert.ErrorContains(t, err, "invalid App Configuration store name")
+	_, err = NewAppClient(nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,104 @@
+package azure
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	throttledRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "appconfig_throttled_requests_total",
+		Help: "Number of App Configuration requests rejected with 429 or 503.",
+	}, []string{"store", "code"})
+	rateLimitWait = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "appconfig_rate_limit_wait_seconds_total",
+		Help: "Time App Configuration requests waited for the client side rate limiter.",
+	}, []string{"store"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(throttledRequests, rateLimitWait)
+}
+
+// throttlePolicy is a pipeline policy sharing one token bucket between all
+// requests to a store and counting the requests the store throttled.
+type throttlePolicy struct {
+	store   string
+	limiter *rate.Limiter
+}
+
+func newThrottlePolicy(store string) *throttlePolicy {
+	return &throttlePolicy{store: store, limiter: rate.NewLimiter(rate.Inf, 0)}
+}
+
+func (p *throttlePolicy) Do(req *policy.Request) (*http.Response, error) {
+	start := time.Now()
+	if err := p.limiter.Wait(req.Raw().Context()); err != nil {
+		return nil, err
+	}
+	if waited := time.Since(start); waited > time.Millisecond {
+		rateLimitWait.WithLabelValues(p.store).Add(waited.Seconds())
+	}
+
+	resp, err := req.Next()
+	if resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+		throttledRequests.WithLabelValues(p.store, strconv.Itoa(resp.StatusCode)).Inc()
+	}
+	return resp, err
+}
+
+// SetRateLimit limits the requests to the store to qps per second with bursts
+// of up to burst requests. A qps of 0 disables the limit.
+func (cli *AppConfigClient) SetRateLimit(qps float64, burst int) {
+	if qps <= 0 {
+		cli.throttle.limiter.SetLimit(rate.Inf)
+		return
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	cli.throttle.limiter.SetBurst(burst)
+	cli.throttle.limiter.SetLimit(rate.Limit(qps))
+}
+
+// RetryAfter returns the delay the store asked for with the Retry-After
+// header of a throttled request, or 0 if the error has none.
+func RetryAfter(err error) time.Duration {
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) || respErr.RawResponse == nil {
+		return 0
+	}
+	return retryAfter(respErr.RawResponse.Header)
+}
+
+func retryAfter(header http.Header) time.Duration {
+	for _, h := range []string{"Retry-After-Ms", "X-Ms-Retry-After-Ms"} {
+		if ms, err := strconv.Atoi(header.Get(h)); err == nil && ms > 0 {
+			return time.Duration(ms) * time.Millisecond
+		}
+	}
+	v := header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		if s <= 0 {
+			return 0
+		}
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,70 @@
+package azure
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryAfter(t *testing.T) {
+	assert.Equal(t, 7*time.Second, retryAfter(http.Header{"Retry-After": {"7"}}))
+	assert.Equal(t, 250*time.Millisecond, retryAfter(http.Header{"Retry-After-Ms": {"250"}, "Retry-After": {"7"}}))
+	assert.Equal(t, 250*time.Millisecond, retryAfter(http.Header{"X-Ms-Retry-After-Ms": {"250"}}))
+	assert.InDelta(t, 10*time.Second, retryAfter(http.Header{"Retry-After": {time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)}}), float64(time.Second))
+	assert.Equal(t, time.Duration(0), retryAfter(http.Header{"Retry-After": {"soon"}}))
+	assert.Equal(t, time.Duration(0), retryAfter(http.Header{}))
+}
+
+func TestThrottledRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Sync-Token", "id=value;sn=0")
+		rw.Header().Set("Retry-After-Ms", "1")
+		rw.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	t.Setenv("LOCAL_STACK_ENDPOINT", server.URL)
+
+	appConfig, err := NewAppClient(nil)
+	assert.Nil(t, err)
+
+	throttled := throttledRequests.WithLabelValues(appConfig.throttle.store, "429")
+	before := testutil.ToFloat64(throttled)
+
+	_, ssmErr := appConfig.GetParameter("user")
+	assert.ErrorIs(t, ssmErr, ErrThrottled)
+	assert.Equal(t, time.Millisecond, RetryAfter(ssmErr))
+	// The request and the retries of the azcore pipeline are counted.
+	assert.Equal(t, before+4, testutil.ToFloat64(throttled))
+}
+
+func TestSetRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Sync-Token", "id=value;sn=0")
+		_, _ = rw.Write([]byte(`{"key": "user", "value": "dbuser"}`))
+	}))
+	defer server.Close()
+	t.Setenv("LOCAL_STACK_ENDPOINT", server.URL)
+
+	appConfig, err := NewAppClient(nil)
+	assert.Nil(t, err)
+	appConfig.SetRateLimit(20, 1)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, ssmErr := appConfig.GetParameter("user")
+		assert.Nil(t, ssmErr)
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+
+	appConfig.SetRateLimit(0, 0)
+	start = time.Now()
+	for i := 0; i < 10; i++ {
+		_, ssmErr := appConfig.GetParameter("user")
+		assert.Nil(t, ssmErr)
+	}
+	assert.Less(t, time.Since(start), 90*time.Millisecond)
+}
@@ -0,0 +1,99 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/fr123k/az-app-config-operator/pkg/azure"
+
+// WithContext returns a client sending its requests with the ctx, so the
+// spans of the requests are children of the span in the ctx. The client shares
+// the cache and the rate limit with cli.
+func (cli *AppConfigClient) WithContext(ctx context.Context) *AppConfigClient {
+	c := *cli
+	c.ctx = ctx
+	return &c
+}
+
+// WithContext returns a client sending its requests with the ctx.
+func (kv *KeyVaultClient) WithContext(ctx context.Context) *KeyVaultClient {
+	c := *kv
+	c.ctx = ctx
+	return &c
+}
+
+// startSpan starts a span of the global tracer provider, which is looked up on
+// every call so it can be set after the clients were created.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records the error, if any, and ends the span.
+func endSpan(span trace.Span, err *SSMError) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracingProvider passes the spans of the azcore pipeline, one per request and
+// retry, to the global OpenTelemetry tracer provider.
+func tracingProvider() tracing.Provider {
+	return tracing.NewProvider(func(name, version string) tracing.Tracer {
+		return tracing.NewTracer(func(ctx context.Context, spanName string, options *tracing.SpanOptions) (context.Context, tracing.Span) {
+			var opts []trace.SpanStartOption
+			// The span kinds and status codes of azcore have the values of OpenTelemetry.
+			if options != nil {
+				opts = append(opts, trace.WithSpanKind(trace.SpanKind(options.Kind)), trace.WithAttributes(attributes(options.Attributes)...))
+			}
+			ctx, span := otel.Tracer(name, trace.WithInstrumentationVersion(version)).Start(ctx, spanName, opts...)
+			return ctx, newSpan(span)
+		}, &tracing.TracerOptions{
+			SpanFromContext: func(ctx context.Context) tracing.Span {
+				return newSpan(trace.SpanFromContext(ctx))
+			},
+		})
+	}, nil)
+}
+
+func newSpan(span trace.Span) tracing.Span {
+	return tracing.NewSpan(tracing.SpanImpl{
+		End:           func() { span.End() },
+		SetAttributes: func(attrs ...tracing.Attribute) { span.SetAttributes(attributes(attrs)...) },
+		AddEvent: func(name string, attrs ...tracing.Attribute) {
+			span.AddEvent(name, trace.WithAttributes(attributes(attrs)...))
+		},
+		SetStatus: func(status tracing.SpanStatus, desc string) {
+			span.SetStatus(codes.Code(status), desc)
+		},
+	})
+}
+
+func attributes(attrs []tracing.Attribute) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		switch v := a.Value.(type) {
+		case string:
+			kvs = append(kvs, attribute.String(a.Key, v))
+		case int:
+			kvs = append(kvs, attribute.Int(a.Key, v))
+		case int64:
+			kvs = append(kvs, attribute.Int64(a.Key, v))
+		case float64:
+			kvs = append(kvs, attribute.Float64(a.Key, v))
+		case bool:
+			kvs = append(kvs, attribute.Bool(a.Key, v))
+		default:
+			kvs = append(kvs, attribute.String(a.Key, fmt.Sprintf("%v", v)))
+		}
+	}
+	return kvs
+}
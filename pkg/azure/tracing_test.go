@@ -0,0 +1,115 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/fr123k/az-app-config-operator/api/v1alpha1"
+)
+
+func TestGetParameterSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Sync-Token", "id=value;sn=0")
+		rw.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	t.Setenv("LOCAL_STACK_ENDPOINT", server.URL)
+
+	appConfig, err := NewAppClient(nil)
+	assert.Nil(t, err)
+
+	ctx, parent := otel.Tracer("test").Start(context.TODO(), "parent")
+	_, ssmErr := appConfig.WithContext(ctx).GetParameter("missing")
+	parent.End()
+	assert.ErrorIs(t, ssmErr, ErrNotFound)
+
+	spans := exporter.GetSpans()
+	var get tracetest.SpanStub
+	for _, span := range spans {
+		if span.Name == "AppConfigClient.Get" {
+			get = span
+		}
+	}
+	assert.Equal(t, parent.SpanContext().SpanID(), get.Parent.SpanID())
+	assert.Equal(t, codes.Error, get.Status.Code)
+	assert.Contains(t, get.Attributes, attribute.String("appconfig.key", "missing"))
+
+	children := 0
+	for _, span := range spans {
+		if span.Parent.SpanID() == get.SpanContext.SpanID() {
+			children++
+		}
+	}
+	assert.Greater(t, children, 0)
+}
+
+func TestKeyVaultResolutionSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	referenceTestServer(t,
+		map[string]string{"/app/password": `{"uri":"https://payments.vault.azure.net/secrets/db-password"}`},
+		map[string]string{"/app/password": KeyVaultRefContentType})
+	appConfig, err := NewAppClient(nil)
+	assert.Nil(t, err)
+	appConfig.SetKeyVaultResolver(testKeyVaultResolver(map[string]string{"https://payments.vault.azure.net/secrets/db-password": "dbpassword"}))
+
+	ctx, parent := otel.Tracer("test").Start(context.TODO(), "parent")
+	_, ssmErr := appConfig.WithContext(ctx).ParameterStoreRefParameters(v1alpha1.ParameterStoreRef{Name: "/app/password"})
+	parent.End()
+	assert.Nil(t, ssmErr)
+
+	spans := exporter.GetSpans()
+	var resolve tracetest.SpanStub
+	for _, span := range spans {
+		if span.Name == "KeyVaultResolver.Resolve" {
+			resolve = span
+		}
+	}
+	assert.Equal(t, parent.SpanContext().SpanID(), resolve.Parent.SpanID())
+	assert.Contains(t, resolve.Attributes, attribute.String("keyvault.vault", "https://payments.vault.azure.net"))
+	assert.Contains(t, resolve.Attributes, attribute.String("keyvault.secret", "db-password"))
+
+	children := 0
+	for _, span := range spans {
+		if span.Parent.SpanID() == resolve.SpanContext.SpanID() {
+			children++
+		}
+	}
+	assert.Greater(t, children, 0)
+}
+
+func TestAttributes(t *testing.T) {
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("s", "v"),
+		attribute.Int("i", 1),
+		attribute.Int64("i64", 2),
+		attribute.Float64("f", 0.5),
+		attribute.Bool("b", true),
+		attribute.String("o", "[a]"),
+	}, attributes([]tracing.Attribute{
+		{Key: "s", Value: "v"},
+		{Key: "i", Value: 1},
+		{Key: "i64", Value: int64(2)},
+		{Key: "f", Value: 0.5},
+		{Key: "b", Value: true},
+		{Key: "o", Value: []string{"a"}},
+	}))
+}